@@ -17,327 +17,281 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"go/format"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
-	"text/template"
-	"time"
 
+	"github.com/pmezard/go-difflib/difflib"
 	"github.com/spf13/cobra"
-	"github.com/spf13/pflag"
-	"golang.org/x/tools/imports"
+	"github.com/twitchtv/circuitgen/gen"
 )
 
-// circuitWrapperTemplate is a template for generating a circuit wrapper. Conditional logic should be minimized in the
-// template for readability.
-var circuitWrapperTemplate = template.Must(template.New("").Parse(`
-// Code ` + `generated by circuitgen tool. DO NOT EDIT
-
-package {{ .PackageName }}
-
-import (
-	"context"
-	"github.com/cep21/circuit{{ .VersionSuffix }}"
-	{{ range .TypeMetadata.Imports -}}
-		"{{ .Path }}"
-	{{ end -}}
-)
-
-// {{ .WrapperStructName }}Config contains configuration for {{ .WrapperStructName }}. All fields are optional
-type {{ .WrapperStructName }}Config struct {
-	// ShouldSkipError determines whether an error should be skipped and have the circuit
-	// track the call as successful. This takes precedence over IsBadRequest
-	ShouldSkipError func(error) bool
-
-	// IsBadRequest is an optional bad request checker. It is useful to not count user errors as faults
-	IsBadRequest func(error) bool
-
-	// Prefix is prepended to all circuit names
-	Prefix string
-
-	// Defaults are used for all created circuits. Per-circuit configs override this
-	Defaults circuit.Config
-
-	{{ range $i, $meth := .TypeMetadata.Methods -}}
-		{{ if $meth.IsWrappingSupported -}}
-			// Circuit{{ $meth.Name }} is the configuration used for the {{ $meth.Name }} circuit. This overrides values set by Defaults
-			Circuit{{ $meth.Name }} circuit.Config
-		{{ end -}}
-	{{ end }}
-}
-
-// {{ .WrapperStructName }} is a circuit wrapper for {{ .EmbeddedType }}
-type {{ .WrapperStructName }} struct {
-	{{ .EmbeddedType }}
-
-	// ShouldSkipError determines whether an error should be skipped and have the circuit
-	// track the call as successful. This takes precedence over IsBadRequest
-	ShouldSkipError func(error) bool
-
-	// IsBadRequest checks whether to count a user error against the circuit. It is recommended to set this
-	IsBadRequest func(error) bool
-
-	{{ range $i, $meth := .TypeMetadata.Methods -}}
-		{{ if $meth.IsWrappingSupported -}}
-			// Circuit{{ $meth.Name }} is the circuit for method {{ $meth.Name }}
-			Circuit{{ $meth.Name }} *circuit.Circuit
-		{{ end -}}
-	{{ end }}
-}
-
-// New{{ .WrapperStructName }} creates a new circuit wrapper and initializes circuits
-func New{{ .WrapperStructName }}(
-	manager *circuit.Manager,
-	embedded {{ .EmbeddedType }},
-	conf {{ .WrapperStructName }}Config,
-) (*{{ .WrapperStructName }}, error) {
-	if conf.ShouldSkipError == nil {
-		conf.ShouldSkipError = func(err error) bool {
-			return false
-		}
-	}
-
-	if conf.IsBadRequest == nil {
-		conf.IsBadRequest = func(err error) bool {
-			return false
-		}
-	}
-
-	w := &{{ .WrapperStructName }}{
-		{{ .EmbeddedName }}: embedded,
-		ShouldSkipError: conf.ShouldSkipError,
-		IsBadRequest: conf.IsBadRequest,
-	}
-
-	var err error
-	{{ range $i, $meth := .TypeMetadata.Methods -}}
-		{{ if $meth.IsWrappingSupported -}}
-			w.Circuit{{ $meth.Name }}, err = manager.CreateCircuit(conf.Prefix + "{{ $.Alias }}.{{ $meth.Name }}", conf.Circuit{{ $meth.Name}}, conf.Defaults)
-			if err != nil {
-				return nil, err
-			}
-		{{ end }}
-	{{ end }}
-
-	return w, nil
-}
-
-{{ range $i, $meth := .TypeMetadata.Methods }}
-{{ if $meth.IsWrappingSupported -}}
-// {{ $meth.Name }} calls the embedded {{ $.EmbeddedType }}'s method {{ $meth.Name}} with Circuit{{ $meth.Name }}
-func (w *{{ $.WrapperStructName }}) {{ $meth.Name }}({{ $meth.ParamsSignature "ctx"}}) {{ $meth.ResultsSignature }} {
-	{{ $meth.ResultsClosureVariableDeclarations -}}
-	var skippedErr error
-
-	err := w.Circuit{{ $meth.Name }}.Run(ctx, func(ctx context.Context) error {
-		{{ if $meth.HasOneMethodResultVariable -}}
-			err := w.{{ $.EmbeddedName }}.{{ $meth.Name }}({{ $meth.CallSignatureWithClosure }})
-		{{ else -}}
-			var err error
-			{{ $meth.ResultsCircuitVariableAssignments }} = w.{{ $.EmbeddedName }}.{{ $meth.Name }}({{ $meth.CallSignatureWithClosure }})
-		{{ end }}
-
-		if w.ShouldSkipError(err) {
-			skippedErr = err
-			return nil
-		}
-
-		if w.IsBadRequest(err) {
-			return &circuit.SimpleBadRequest{Err: err}
-		}
-		return err
-	})
-
-	if skippedErr != nil {
-		err = skippedErr
-	}
-
-	if berr, ok := err.(*circuit.SimpleBadRequest); ok {
-		err = berr.Err
-	}
-
-	return {{ $meth.ResultsClosureVariableReturns }} err
-}
-{{ end }}
-{{ end }}
-
-{{if .IsInterface -}}
-var _ {{ .EmbeddedType }} = (*{{ .WrapperStructName}})(nil)
-{{ end }}
-`))
-
-type circuitWrapperTemplateContext struct {
-	PackageName   string
-	Alias         string
-	VersionSuffix string
-	TypeMetadata  TypeMetadata
-}
-
-// ex. "dynamodbiface.DynamoDBAPI"
-func (t *circuitWrapperTemplateContext) EmbeddedType() string {
-	if t.IsInterface() {
-		return t.TypeMetadata.TypeInfo.Name
-	}
-	return "*" + t.TypeMetadata.TypeInfo.Name // assume struct with pointer receiver
-}
-
-func (t *circuitWrapperTemplateContext) EmbeddedName() string {
-	return t.TypeMetadata.TypeInfo.NameWithoutQualifier
-}
-
-func (t *circuitWrapperTemplateContext) WrapperStructName() string {
-	return "CircuitWrapper" + t.Alias
-}
-
-func (t *circuitWrapperTemplateContext) IsInterface() bool {
-	return t.TypeMetadata.TypeInfo.IsInterface
-}
-
 type circuitCmd struct {
-	pkg          string
-	name         string
-	out          string
-	alias        string
-	majorVersion int
-	debug        bool
-	goimports    bool
+	gen.Target
+	config    string
+	template  string
+	check     bool
+	dryRun    bool
+	debug     bool
+	goimports bool
 }
 
 func (c *circuitCmd) Cobra() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:     "circuitgen --pkg <package path> --name <type name> --out <output path> [--alias <alias>]",
+		Use:     "circuitgen (--pkg <package path> (--name <type name> | --instantiate <type expression> | --name-regex <regex>) --out <output path> [--alias <alias>] | --config <config path>)",
 		Example: "circuitgen --pkg github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface --name DynamoDBAPI --alias DynamoDB --out internal/wrappers",
 		Short:   "circuitgen is a circuit wrapper generator for interfaces and structs",
+		Args:    cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return c.Execute()
 		},
 		DisableFlagsInUseLine: true,
 	}
 
-	pf := cmd.PersistentFlags()
-	pf.StringVar(&c.pkg, "pkg", "", "(Required) The path to the package. Add ./vendor if the dependency is vendored")
-	markFlagRequired(pf, "pkg")
-
-	pf.StringVar(&c.name, "name", "", "(Required) The name of the type (interface or struct) in the package path")
-	markFlagRequired(pf, "name")
+	// Runs the legacy "circuit" arg of go:generate lines like the root command
+	cmd.AddCommand(&cobra.Command{
+		Use:    "circuit",
+		Args:   cobra.NoArgs,
+		Hidden: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.Execute()
+		},
+		DisableFlagsInUseLine: true,
+	})
 
-	pf.StringVar(&c.out, "out", "", "(Required) The output path. A default filename is given if the path looks like a directory. The path is lazily created (equivalent to mkdir -p)")
-	markFlagRequired(pf, "out")
+	cmd.AddCommand(&cobra.Command{
+		Use:     "scan <package patterns>...",
+		Example: "circuitgen scan ./...",
+		Short:   "Generate the wrappers of all types annotated with a //circuitgen:wrap comment in the packages",
+		Long: "Generate the wrappers of all types annotated with a //circuitgen:wrap comment in the packages. The comment holds the " +
+			"options of the wrapper named like the config file fields, ex. \"//circuitgen:wrap alias=Pubsub out=./wrappers\". " +
+			"Relative output paths are resolved against the directory of the annotated file, which is the default output path",
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.Scan(args)
+		},
+		DisableFlagsInUseLine: true,
+	})
 
-	pf.StringVar(&c.alias, "alias", "", "(Optional) The name used for the generated wrapper in the struct, constructor, and default circuit prefix. Defaults to name")
+	pf := cmd.PersistentFlags()
+	pf.StringVar(&c.Pkg, "pkg", "", "(Required unless --config is set) The path to the package. Add ./vendor if the dependency is vendored")
+	pf.StringVar(&c.Name, "name", "", "(Required unless --instantiate is set) The name of the type (interface or struct) in the package path")
+	pf.StringVar(&c.NameRegex, "name-regex", "", "(Optional) Generate a wrapper for every exported type with methods whose name matches the regular expression, instead of --name. --pkg can be a package pattern, ex. \"./...\", and --out must be a directory. The alias of each wrapper is the first capture group, ex. \"^(.+)API$\", or the type name")
+	pf.StringVar(&c.Instantiate, "instantiate", "", "(Optional) Generate a non-generic wrapper for an instantiation of a generic type, ex. \"Store[string,*model.User]\". Type arguments are resolved in the package path and its imports")
+	pf.StringVar(&c.Out, "out", "", "(Required unless --config is set) The output path. A default filename is given if the path looks like a directory. The path is lazily created (equivalent to mkdir -p). Use - to write to stdout, in the package of the working directory")
+	pf.StringVar(&c.Alias, "alias", "", "(Optional) The name used for the generated wrapper in the struct, constructor, and default circuit prefix. Defaults to name")
+	pf.StringVar(&c.ContextAccessor, "context-accessor", "", "(Optional) An accessor expression resolving a context from a param, ex. \".Context()\". Methods without a context param are wrapped using the first param it resolves on")
+	pf.BoolVar(&c.WrapWithoutContext, "wrap-without-context", false, "(Optional) Wrap methods without a context param that return an error, using the wrapper's base context. Methods can opt in individually with a //circuitgen:wrap-without-context comment")
+	pf.StringArrayVar(&c.Include, "include", nil, "(Optional) Only wrap methods matching any of the patterns. Patterns are globs, ex. \"*WithContext\", or regular expressions wrapped in slashes, ex. \"/^(Get|Put)Item/\". Can be repeated")
+	pf.StringArrayVar(&c.Exclude, "exclude", nil, "(Optional) Don't wrap methods matching any of the patterns. Excluded methods pass through the embedded type. Can be repeated")
+	pf.StringArrayVar(&c.Hedge, "hedge", nil, "(Optional) Generate hedging for idempotent methods matching any of the patterns. Hedging is enabled per method with the wrapper's config. Methods can opt in individually with a //circuitgen:hedge comment. Can be repeated")
+	pf.StringVar(&c.Order, "order", "", "(Optional) The order of the methods in the wrapper. \"alpha\" sorts them by name, and \"source\" follows their declaration order in the source files, with the methods of embedded interfaces in place. Defaults to alpha")
+	pf.StringVar(&c.config, "config", "", "(Optional) A YAML config file listing the targets to generate in one pass. Can't be combined with the flags of a single target")
+	pf.BoolVar(&c.check, "check", false, "(Optional) Render the wrappers in memory and fail with a unified diff if the files at the output paths differ, instead of writing them")
+	pf.BoolVar(&c.dryRun, "dry-run", false, "(Optional) Report the output path, the wrapped and skipped methods, and the imports of each wrapper without writing it")
+	pf.StringVar(&c.template, "template", "", "(Optional) A template file overriding the wrapper template. It can redefine the named blocks of the default template, ex. {{ define \"methodBody\" }}, and keep the rest")
 	pf.BoolVar(&c.debug, "debug", false, "Enable debug logging mode")
 	pf.BoolVar(&c.goimports, "goimports", true, "Enable goimports formatting. If false, uses gofmt")
-	pf.IntVar(&c.majorVersion, "circuit-major-version", 2, "(Optional) The version of cep21/circuit to import. Use 3 or greater for go module compatibility.")
+	pf.IntVar(&c.MajorVersion, "circuit-major-version", 0, "(Optional) The version of cep21/circuit to import. Use 3 or greater for go module compatibility. Defaults to 2")
 
 	return cmd
 }
 
-func markFlagRequired(pf *pflag.FlagSet, name string) {
-	err := cobra.MarkFlagRequired(pf, name)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "error marking %s flag as required\n", name)
-		os.Exit(1)
-	}
-}
-
 func (c *circuitCmd) Execute() error {
-	if c.alias == "" {
-		c.alias = c.name
-	}
+	targets := []gen.Target{c.Target}
+	if c.config != "" {
+		if !reflect.DeepEqual(c.Target, gen.Target{}) {
+			return errors.New("--config can't be combined with the flags of a single target")
+		}
 
-	if !strings.HasSuffix(c.out, ".go") {
-		c.out = filepath.Join(c.out, strings.ToLower(c.alias)+".gen.go")
+		conf, err := readConfig(c.config)
+		if err != nil {
+			return err
+		}
+		targets = conf.Targets
 	}
 
-	if err := c.gen(); err != nil {
-		return fmt.Errorf("generating circuit wrapper: %v", err)
+	// Targets are normalized again by the generator, but errors are reported here with their index in the config
+	for i := range targets {
+		if err := targets[i].Normalize(); err != nil {
+			if c.config != "" {
+				return fmt.Errorf("target %d in %s: %v", i+1, c.config, err)
+			}
+			return err
+		}
 	}
 
-	return nil
+	return c.runTargets(targets)
 }
 
-func (c *circuitCmd) gen() error {
-	s := time.Now()
-	pkgs, err := loadPackages(c.pkg)
-	if err != nil {
-		return err
+// Scan generates the wrappers of the types annotated with wrap directives in the packages matching the patterns.
+func (c *circuitCmd) Scan(patterns []string) error {
+	flags := c.Target
+	flags.MajorVersion = 0
+	flags.Order = ""
+	if c.config != "" || !reflect.DeepEqual(flags, gen.Target{}) {
+		return errors.New("scan can't be combined with --config or the flags of a single target")
 	}
-	c.log("loadPackages took %v", time.Since(s))
 
-	err = firstPackagesError(pkgs)
+	targets, err := gen.Scan(patterns, gen.ScanOptions{MajorVersion: c.MajorVersion, Order: c.Order})
 	if err != nil {
 		return err
 	}
-
-	pkg := pkgs[0]
-
-	obj := pkg.Types.Scope().Lookup(c.name)
-	if obj == nil {
-		return errors.New("could not lookup name")
+	if len(targets) == 0 {
+		return fmt.Errorf("no types with a //circuitgen:wrap comment in %s", strings.Join(patterns, " "))
 	}
 
-	typ := obj.Type()
-	if typ == nil {
-		return errors.New("object is not a type")
+	return c.runTargets(targets)
+}
+
+// runTargets checks, reports, or generates the wrappers of the targets
+func (c *circuitCmd) runTargets(targets []gen.Target) error {
+	if c.check && c.dryRun {
+		return errors.New("--check can't be combined with --dry-run")
 	}
 
-	s = time.Now()
-	outPkgPath, err := resolvePackagePath(c.out)
+	g, err := c.generator()
 	if err != nil {
 		return err
 	}
-	c.log("resolvePackagePath took %v", time.Since(s))
 
-	outPkgName := filepath.Base(outPkgPath)
-
-	s = time.Now()
-	typeMeta, err := parseType(typ, outPkgPath)
+	wrappers, err := g.Generate(targets...)
 	if err != nil {
-		return err
+		return fmt.Errorf("generating circuit wrapper: %v", err)
 	}
-	c.log("parseType took %v", time.Since(s))
 
-	templateCtx := circuitWrapperTemplateContext{
-		PackageName:   outPkgName,
-		VersionSuffix: circuitVersionSuffix(c.majorVersion),
-		TypeMetadata:  typeMeta,
-		Alias:         c.alias,
+	if c.check {
+		return checkWrappers(wrappers, os.Stdout)
 	}
 
-	s = time.Now()
-	var b bytes.Buffer
-	err = circuitWrapperTemplate.Execute(&b, &templateCtx)
-	if err != nil {
-		return fmt.Errorf("rendering circuit wrapper: %v", err)
+	if c.dryRun {
+		return reportWrappers(wrappers, os.Stdout)
 	}
-	c.log("executing circuit wrapper template took %v", time.Since(s))
-
-	s = time.Now()
-	var src []byte
-	if c.goimports {
-		src, err = imports.Process("<gen>", b.Bytes(), nil)
-	} else {
-		src, err = format.Source(b.Bytes())
+
+	return writeWrappers(wrappers)
+}
+
+// generator creates the generator of the wrappers with the flags
+func (c *circuitCmd) generator() (*gen.Generator, error) {
+	opts := gen.Options{Goimports: c.goimports}
+	if c.template != "" {
+		b, err := ioutil.ReadFile(c.template) // #nosec G304 path is provided by the user
+		if err != nil {
+			return nil, fmt.Errorf("reading template: %v", err)
+		}
+		opts.Template = string(b)
 	}
-	if err != nil {
-		return fmt.Errorf("formatting rendered circuit wrapper: %v", err)
+	if c.debug {
+		// Stdout may be the generated wrapper with --out -
+		opts.Logf = func(msg string, args ...interface{}) {
+			fmt.Fprintf(os.Stderr, "[debug] "+msg+"\n", args...)
+		}
 	}
-	c.log("formatting code took %v", time.Since(s))
 
-	err = writeFile(c.out, src)
-	if err != nil {
-		return fmt.Errorf("writing circuit wrapper file: %v", err)
+	return gen.New(opts), nil
+}
+
+// writeWrappers writes the wrappers to their output paths
+func writeWrappers(wrappers []gen.Wrapper) error {
+	for _, w := range wrappers {
+		var err error
+		if w.Target.Out == gen.StdoutPath {
+			_, err = os.Stdout.Write(w.Source)
+		} else {
+			err = writeFile(w.Target.Out, w.Source)
+		}
+		if err != nil {
+			return fmt.Errorf("writing circuit wrapper file: %v", err)
+		}
 	}
 
 	return nil
 }
 
-func (c *circuitCmd) log(msg string, args ...interface{}) {
-	if c.debug {
-		fmt.Printf("[debug] "+msg+"\n", args...)
+// checkWrappers writes a unified diff to w for every file at the output path of a wrapper that differs from it. An
+// error is returned if any are stale.
+func checkWrappers(wrappers []gen.Wrapper, w io.Writer) error {
+	var stale []string
+	for _, wrapper := range wrappers {
+		out := wrapper.Target.Out
+		if out == gen.StdoutPath {
+			return errors.New("--check requires output paths")
+		}
+
+		src := wrapper.Source
+		current, err := ioutil.ReadFile(out) // #nosec G304 path is provided by the user
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("reading circuit wrapper file: %v", err)
+		}
+		if bytes.Equal(current, src) {
+			continue
+		}
+
+		stale = append(stale, out)
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(string(current)),
+			B:        difflib.SplitLines(string(src)),
+			FromFile: out,
+			ToFile:   out + " (generated)",
+			Context:  3,
+		})
+		if err != nil {
+			return fmt.Errorf("diffing circuit wrapper file: %v", err)
+		}
+		if _, err := io.WriteString(w, diff); err != nil {
+			return err
+		}
+	}
+
+	if len(stale) > 0 {
+		return fmt.Errorf("circuit wrappers are stale, regenerate them: %s", strings.Join(stale, ", "))
+	}
+
+	return nil
+}
+
+// reportWrappers writes the output paths, wrapped and skipped methods, and imports of the wrappers to w
+func reportWrappers(wrappers []gen.Wrapper, w io.Writer) error {
+	var b bytes.Buffer
+	for _, wrapper := range wrappers {
+		fmt.Fprintf(&b, "%s\n", wrapper.Target.Out)
+
+		fmt.Fprintf(&b, "  wrapped methods:\n")
+		for _, m := range wrapper.WrappedMethods() {
+			var notes []string
+			if m.Hedged {
+				notes = append(notes, "hedged")
+			}
+			if m.Timeout > 0 {
+				notes = append(notes, "timeout "+m.Timeout.String())
+			}
+			if len(notes) > 0 {
+				fmt.Fprintf(&b, "    %s (%s)\n", m.Name, strings.Join(notes, ", "))
+			} else {
+				fmt.Fprintf(&b, "    %s\n", m.Name)
+			}
+		}
+
+		fmt.Fprintf(&b, "  skipped methods:\n")
+		for _, m := range wrapper.SkippedMethods() {
+			fmt.Fprintf(&b, "    %s: %s\n", m.Name, m.SkipReason)
+		}
+
+		fmt.Fprintf(&b, "  imports:\n")
+		for _, imp := range wrapper.Imports {
+			if imp.Alias != "" {
+				fmt.Fprintf(&b, "    %s %s\n", imp.Alias, imp.Path)
+			} else {
+				fmt.Fprintf(&b, "    %s\n", imp.Path)
+			}
+		}
 	}
+
+	_, err := w.Write(b.Bytes())
+	return err
 }
 
 // Writes the src to the path. The directory is lazily created for the path (equivalent to `mkdir -p`)
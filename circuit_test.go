@@ -0,0 +1,123 @@
+// Copyright 2019 Twitch Interactive, Inc.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the License is
+// located at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// or in the "license" file accompanying this file. This file is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/twitchtv/circuitgen/gen"
+)
+
+func TestCheckWrappers(t *testing.T) {
+	g := gen.New(gen.Options{})
+	shape := gen.Target{
+		Pkg:          "./gen/testdata/shapes",
+		Name:         "ChanShape",
+		Alias:        "ChanShape",
+		Out:          filepath.Join("gen", "testdata", "golden", "chanshape.gen.go"),
+		MajorVersion: 3,
+	}
+
+	// The golden file is the up to date wrapper
+	golden, err := ioutil.ReadFile(filepath.Join("gen", "testdata", "golden", "chanshape.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFile(shape.Out, golden); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.Remove(shape.Out); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	wrappers, err := g.Generate(shape)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var diff bytes.Buffer
+	if err := checkWrappers(wrappers, &diff); err != nil {
+		t.Fatalf("expected the golden file to be up to date: %v\n%s", err, diff.String())
+	}
+	if diff.Len() != 0 {
+		t.Errorf("expected no diff, got:\n%s", diff.String())
+	}
+
+	missing := shape
+	missing.Out = filepath.Join("gen", "testdata", "golden", "missing.gen.go")
+	wrappers, err = g.Generate(shape, missing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkWrappers(wrappers, &diff); err == nil {
+		t.Fatal("expected an error for a missing wrapper")
+	}
+	if !strings.Contains(diff.String(), "+++ "+missing.Out+" (generated)") {
+		t.Errorf("expected a unified diff for %s, got:\n%s", missing.Out, diff.String())
+	}
+	if _, err := ioutil.ReadFile(missing.Out); err == nil {
+		t.Errorf("expected %s not to be written", missing.Out)
+	}
+}
+
+func TestReportWrappers(t *testing.T) {
+	shape := gen.Target{
+		Pkg:          "./gen/testdata/shapes",
+		Name:         "ChanShape",
+		Alias:        "ChanShape",
+		Out:          filepath.Join("gen", "testdata", "golden", "chanshape.gen.go"),
+		MajorVersion: 3,
+		Exclude:      []string{"Send"},
+	}
+
+	wrappers, err := gen.New(gen.Options{}).Generate(shape)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report bytes.Buffer
+	if err := reportWrappers(wrappers, &report); err != nil {
+		t.Fatal(err)
+	}
+
+	// Excluded methods don't add imports
+	want := shape.Out + `
+  wrapped methods:
+    Subscribe
+  skipped methods:
+    Send: excluded by method filters
+  imports:
+    context
+    errors
+    fmt
+    math/rand
+    runtime/debug
+    sync
+    time
+    github.com/cep21/circuit/v3
+    github.com/twitchtv/circuitgen/gen/testdata/shapes
+    github.com/twitchtv/circuitgen/internal/circuitgentest/model
+`
+	if report.String() != want {
+		t.Errorf("expected report:\n%s\ngot:\n%s", want, report.String())
+	}
+	if _, err := ioutil.ReadFile(shape.Out); err == nil {
+		t.Errorf("expected %s not to be written", shape.Out)
+	}
+}
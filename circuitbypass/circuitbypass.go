@@ -0,0 +1,40 @@
+// Copyright 2019 Twitch Interactive, Inc.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the License is
+// located at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// or in the "license" file accompanying this file. This file is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package circuitbypass marks contexts of calls that bypass the circuits of circuitgen wrappers.
+//
+// Wrappers check contexts with the Bypass func of their config, so set it to IsBypassed:
+//
+//	client, err := wrappers.NewCircuitWrapperDynamoDB(manager, dynamoClient, wrappers.CircuitWrapperDynamoDBConfig{
+//		Bypass: circuitbypass.IsBypassed,
+//	})
+//
+// Calls with a context from WithCircuitBypass then call the embedded method directly. They are never short-circuited
+// and aren't tracked by the circuits.
+package circuitbypass
+
+import "context"
+
+type bypassKey struct{}
+
+// WithCircuitBypass returns a copy of the context whose calls bypass the circuits of wrappers. ex. for admin tools
+// or backfills that shouldn't be rejected by open circuits or affect their stats
+func WithCircuitBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassKey{}, true)
+}
+
+// IsBypassed returns whether calls with the context bypass the circuits of wrappers
+func IsBypassed(ctx context.Context) bool {
+	v, ok := ctx.Value(bypassKey{}).(bool)
+	return ok && v
+}
@@ -0,0 +1,38 @@
+// Copyright 2019 Twitch Interactive, Inc.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the License is
+// located at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// or in the "license" file accompanying this file. This file is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package circuitbypass
+
+import (
+	"context"
+	"testing"
+)
+
+func TestWithCircuitBypass(t *testing.T) {
+	ctx := context.Background()
+	if IsBypassed(ctx) {
+		t.Error("expected background context not to be bypassed")
+	}
+
+	bypassed := WithCircuitBypass(ctx)
+	if !IsBypassed(bypassed) {
+		t.Error("expected context to be bypassed")
+	}
+
+	// Derived contexts are bypassed too
+	child, cancel := context.WithCancel(bypassed)
+	defer cancel()
+	if !IsBypassed(child) {
+		t.Error("expected derived context to be bypassed")
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2019 Twitch Interactive, Inc.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the License is
+// located at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// or in the "license" file accompanying this file. This file is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/twitchtv/circuitgen/gen"
+	"gopkg.in/yaml.v3"
+)
+
+// config is a config file listing the targets to generate in one pass. ex.
+//
+//	circuit-major-version: 3
+//	targets:
+//	  - pkg: github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface
+//	    name: DynamoDBAPI
+//	    alias: DynamoDB
+//	    out: internal/wrappers
+type config struct {
+	// The default version of cep21/circuit to import for targets that don't set it
+	MajorVersion int `yaml:"circuit-major-version"`
+
+	// The default order of the methods for targets that don't set it
+	Order string `yaml:"order"`
+
+	// Targets to generate
+	Targets []gen.Target `yaml:"targets"`
+}
+
+// readConfig reads the config file at the path. Relative paths of targets are resolved against the directory of the
+// config file, and the default major version is applied.
+func readConfig(path string) (config, error) {
+	b, err := ioutil.ReadFile(path) // #nosec G304 path is provided by the user
+	if err != nil {
+		return config{}, err
+	}
+
+	var conf config
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&conf); err != nil {
+		return config{}, fmt.Errorf("parsing config %s: %v", path, err)
+	}
+	if len(conf.Targets) == 0 {
+		return config{}, fmt.Errorf("config %s has no targets", path)
+	}
+
+	dir := filepath.Dir(path)
+	for i := range conf.Targets {
+		t := &conf.Targets[i]
+		// Package paths are only relative to the config if they look like a relative directory
+		if strings.HasPrefix(t.Pkg, ".") {
+			t.Pkg = relativeDir(filepath.Join(dir, t.Pkg))
+		}
+		if t.Out != "" && t.Out != gen.StdoutPath && !filepath.IsAbs(t.Out) {
+			// Join cleans the path, so keep the trailing separator of a directory
+			out := filepath.Join(dir, t.Out)
+			if strings.HasSuffix(t.Out, "/") {
+				out += "/"
+			}
+			t.Out = out
+		}
+		if t.MajorVersion == 0 {
+			t.MajorVersion = conf.MajorVersion
+		}
+		if t.Order == "" {
+			t.Order = conf.Order
+		}
+	}
+
+	return conf, nil
+}
+
+// relativeDir keeps a relative directory looking like one. Join drops the leading "./", which makes the loader treat
+// "internal/store" as an import path instead of a directory.
+func relativeDir(dir string) string {
+	if filepath.IsAbs(dir) || dir == "." || dir == ".." || strings.HasPrefix(dir, ".."+string(filepath.Separator)) {
+		return dir
+	}
+	return "." + string(filepath.Separator) + dir
+}
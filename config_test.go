@@ -0,0 +1,117 @@
+// Copyright 2019 Twitch Interactive, Inc.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the License is
+// located at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// or in the "license" file accompanying this file. This file is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/twitchtv/circuitgen/gen"
+)
+
+func TestReadConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "circuitgen.yaml")
+	writeConfig := func(t *testing.T, src string) {
+		if err := ioutil.WriteFile(path, []byte(src), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("relative paths and defaults", func(t *testing.T) {
+		writeConfig(t, `
+circuit-major-version: 3
+order: source
+targets:
+  - pkg: ./store
+    name: Store
+    out: wrappers/
+  - pkg: github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface
+    name: DynamoDBAPI
+    alias: DynamoDB
+    out: /tmp/dynamodb.gen.go
+    circuit-major-version: 2
+    order: alpha
+`)
+		conf, err := readConfig(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []gen.Target{
+			{Pkg: filepath.Join(dir, "store"), Name: "Store", Out: filepath.Join(dir, "wrappers") + "/", MajorVersion: 3, Order: "source"},
+			{Pkg: "github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface", Name: "DynamoDBAPI", Alias: "DynamoDB", Out: "/tmp/dynamodb.gen.go", MajorVersion: 2, Order: "alpha"},
+		}
+		if len(conf.Targets) != len(want) {
+			t.Fatalf("expected %d targets, got %d", len(want), len(conf.Targets))
+		}
+		for i := range want {
+			if !reflect.DeepEqual(conf.Targets[i], want[i]) {
+				t.Errorf("target %d: expected %+v, got %+v", i, want[i], conf.Targets[i])
+			}
+		}
+
+		first := conf.Targets[0]
+		if err := first.Normalize(); err != nil {
+			t.Fatal(err)
+		}
+		if first.Alias != "Store" || first.Out != filepath.Join(dir, "wrappers", "store.gen.go") {
+			t.Errorf("unexpected normalized target %+v", first)
+		}
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		writeConfig(t, `
+targets:
+  - pkg: ./store
+    nme: Store
+    out: ./
+`)
+		if _, err := readConfig(path); err == nil {
+			t.Error("expected an error for an unknown field")
+		}
+	})
+
+	t.Run("no targets", func(t *testing.T) {
+		writeConfig(t, "circuit-major-version: 3\n")
+		if _, err := readConfig(path); err == nil {
+			t.Error("expected an error for a config without targets")
+		}
+	})
+}
+
+func TestReadConfigFromSubdirectory(t *testing.T) {
+	path := filepath.Join("internal", "circuitgentest", "circuittest", "circuitgen.yaml")
+	conf, err := readConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The package stays a relative directory instead of turning into an import path
+	if want := "." + string(filepath.Separator) + filepath.Join("internal", "circuitgentest"); conf.Targets[0].Pkg != want {
+		t.Errorf("expected pkg %s, got %s", want, conf.Targets[0].Pkg)
+	}
+
+	wrappers, err := gen.New(gen.Options{Goimports: true}).Generate(conf.Targets...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var diff bytes.Buffer
+	if err := checkWrappers(wrappers, &diff); err != nil {
+		t.Fatalf("expected the wrappers to be up to date: %v\n%s", err, diff.String())
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2019 Twitch Interactive, Inc.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the License is
+// located at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// or in the "license" file accompanying this file. This file is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package gen
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// directivePrefix starts comment lines that configure generation. ex. "//circuitgen:wrap-without-context"
+const directivePrefix = "//circuitgen:"
+
+// Method directives
+const (
+	// Wraps a method without a context param with the base context
+	directiveWrapWithoutContext = "wrap-without-context"
+
+	// Hedges calls of an idempotent method, optionally with the default delay as the value. ex. "hedge=50ms"
+	directiveHedge = "hedge"
+
+	// Passes a method through to the embedded type without wrapping it by a circuit
+	directiveSkip = "skip"
+
+	// Sets the default timeout of the method's circuit. ex. "timeout=200ms"
+	directiveTimeout = "timeout"
+)
+
+// Type directives
+const (
+	// Generates a wrapper for the type with the scan command, with the target options as the value.
+	// ex. "wrap alias=Pubsub out=./wrappers"
+	directiveWrap = "wrap"
+)
+
+// directive is a comment directive. ex. "//circuitgen:timeout=200ms" has the name "timeout" and value "200ms", and
+// "//circuitgen:wrap alias=Pubsub" has the name "wrap" and value "alias=Pubsub"
+type directive struct {
+	name  string
+	value string
+}
+
+// parseDirective parses a comment line as a directive.
+func parseDirective(text string) (directive, bool) {
+	if !strings.HasPrefix(text, directivePrefix) {
+		return directive{}, false
+	}
+	text = strings.TrimSpace(strings.TrimPrefix(text, directivePrefix))
+
+	i := strings.IndexAny(text, "= \t")
+	if i < 0 {
+		return directive{name: text}, text != ""
+	}
+
+	return directive{
+		name:  text[:i],
+		value: strings.TrimSpace(text[i+1:]),
+	}, i > 0
+}
+
+// parseCommentDirectives parses all directives in the comment groups.
+func parseCommentDirectives(groups ...*ast.CommentGroup) []directive {
+	var ds []directive
+	for _, g := range groups {
+		if g == nil {
+			continue
+		}
+		for _, c := range g.List {
+			if d, ok := parseDirective(c.Text); ok {
+				ds = append(ds, d)
+			}
+		}
+	}
+
+	return ds
+}
+
+// methodDirectives maps the positions of the names of methods declared in the files, either on interfaces or with
+// receivers, to the directives in their comments.
+func methodDirectives(files []*ast.File) map[token.Pos][]directive {
+	m := map[token.Pos][]directive{}
+
+	for _, f := range files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.FuncDecl:
+				if n.Recv != nil {
+					if ds := parseCommentDirectives(n.Doc); len(ds) > 0 {
+						m[n.Name.Pos()] = ds
+					}
+				}
+			case *ast.InterfaceType:
+				for _, field := range n.Methods.List {
+					ds := parseCommentDirectives(field.Doc, field.Comment)
+					if len(ds) == 0 {
+						continue
+					}
+					for _, name := range field.Names {
+						m[name.Pos()] = ds
+					}
+				}
+			}
+			return true
+		})
+	}
+
+	return m
+}
+
+// hasDirective returns whether a directive with the name is in ds.
+func hasDirective(ds []directive, name string) bool {
+	_, ok := findDirective(ds, name)
+	return ok
+}
+
+// findDirective returns the first directive with the name in ds.
+func findDirective(ds []directive, name string) (directive, bool) {
+	for _, d := range ds {
+		if d.name == name {
+			return d, true
+		}
+	}
+
+	return directive{}, false
+}
@@ -0,0 +1,104 @@
+// Copyright 2019 Twitch Interactive, Inc.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the License is
+// located at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// or in the "license" file accompanying this file. This file is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package gen
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// methodFilter selects the methods wrapped by circuits by name. A method is wrapped if it matches any include pattern,
+// or there are none, and it matches no exclude pattern.
+type methodFilter struct {
+	include []namePattern
+	exclude []namePattern
+}
+
+// newMethodFilter compiles the include and exclude patterns. See parseNamePattern for the pattern syntax.
+func newMethodFilter(include, exclude []string) (methodFilter, error) {
+	var f methodFilter
+	var err error
+	f.include, err = parseNamePatterns(include)
+	if err != nil {
+		return methodFilter{}, err
+	}
+	f.exclude, err = parseNamePatterns(exclude)
+	if err != nil {
+		return methodFilter{}, err
+	}
+	return f, nil
+}
+
+// matches returns true if the method name passes the filter
+func (f methodFilter) matches(name string) bool {
+	if len(f.include) > 0 && !matchesAny(f.include, name) {
+		return false
+	}
+	return !matchesAny(f.exclude, name)
+}
+
+func matchesAny(patterns []namePattern, name string) bool {
+	for _, p := range patterns {
+		if p.match(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// namePattern matches names with either a glob or a regular expression
+type namePattern struct {
+	glob string
+	re   *regexp.Regexp
+}
+
+// parseNamePattern parses a pattern wrapped in slashes as a regular expression, ex. "/^Get.*WithContext$/". Other
+// patterns are globs matching the whole name, ex. "*PagesWithContext". See path.Match for the glob syntax.
+func parseNamePattern(p string) (namePattern, error) {
+	if len(p) > 1 && strings.HasPrefix(p, "/") && strings.HasSuffix(p, "/") {
+		re, err := regexp.Compile(p[1 : len(p)-1])
+		if err != nil {
+			return namePattern{}, fmt.Errorf("invalid method pattern %s: %v", p, err)
+		}
+		return namePattern{re: re}, nil
+	}
+
+	if _, err := path.Match(p, ""); err != nil {
+		return namePattern{}, fmt.Errorf("invalid method pattern %s: %v", p, err)
+	}
+	return namePattern{glob: p}, nil
+}
+
+func parseNamePatterns(ps []string) ([]namePattern, error) {
+	patterns := make([]namePattern, 0, len(ps))
+	for _, p := range ps {
+		np, err := parseNamePattern(p)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, np)
+	}
+	return patterns, nil
+}
+
+func (p namePattern) match(name string) bool {
+	if p.re != nil {
+		return p.re.MatchString(name)
+	}
+	// The pattern is validated when parsed, so an error can't match anything
+	ok, err := path.Match(p.glob, name)
+	return err == nil && ok
+}
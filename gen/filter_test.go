@@ -0,0 +1,58 @@
+// Copyright 2019 Twitch Interactive, Inc.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the License is
+// located at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// or in the "license" file accompanying this file. This file is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package gen
+
+import "testing"
+
+func TestMethodFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		method  string
+		want    bool
+	}{
+		{name: "no patterns", method: "GetItemWithContext", want: true},
+		{name: "included glob", include: []string{"*WithContext"}, method: "GetItemWithContext", want: true},
+		{name: "not included glob", include: []string{"*WithContext"}, method: "GetItem", want: false},
+		{name: "glob matches whole name", include: []string{"GetItem"}, method: "GetItemWithContext", want: false},
+		{name: "excluded glob", exclude: []string{"*PagesWithContext"}, method: "QueryPagesWithContext", want: false},
+		{name: "exclude wins", include: []string{"*WithContext"}, exclude: []string{"*PagesWithContext"}, method: "QueryPagesWithContext", want: false},
+		{name: "included regex", include: []string{"/^(Get|Put)Item/"}, method: "PutItemWithContext", want: true},
+		{name: "not included regex", include: []string{"/^(Get|Put)Item/"}, method: "DeleteItemWithContext", want: false},
+		{name: "any include", include: []string{"GetItem*", "/^Query/"}, method: "QueryWithContext", want: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := newMethodFilter(tt.include, tt.exclude)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.matches(tt.method); got != tt.want {
+				t.Errorf("expected %v for %s, got %v", tt.want, tt.method, got)
+			}
+		})
+	}
+}
+
+func TestMethodFilterInvalidPattern(t *testing.T) {
+	if _, err := newMethodFilter([]string{"[Get"}, nil); err == nil {
+		t.Error("expected an error for an invalid glob")
+	}
+	if _, err := newMethodFilter(nil, []string{"/(Get/"}); err == nil {
+		t.Error("expected an error for an invalid regex")
+	}
+}
@@ -0,0 +1,334 @@
+// Copyright 2019 Twitch Interactive, Inc.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the License is
+// located at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// or in the "license" file accompanying this file. This file is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package gen generates circuit wrappers of Go types. It's the library behind the circuitgen command, for tools that
+// generate wrappers without running it. ex.
+//
+//	g := gen.New(gen.Options{Goimports: true})
+//	wrappers, err := g.Generate(gen.Target{
+//		Pkg:   "github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface",
+//		Name:  "DynamoDBAPI",
+//		Alias: "DynamoDB",
+//		Out:   "internal/wrappers",
+//	})
+package gen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/types"
+	"path/filepath"
+	"text/template"
+	"time"
+
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/imports"
+)
+
+// Options configures a Generator
+type Options struct {
+	// Whether to format wrappers with goimports, which also removes unused imports. Wrappers are formatted with gofmt
+	// if false
+	Goimports bool
+
+	// Optional function logging the steps of generating, ex. how long loading the packages took
+	Logf func(format string, args ...interface{})
+
+	// Optional source of a template overriding the default template. It is parsed into a copy of the default
+	// template, so it can redefine the default's named blocks, ex. {{ define "methodBody" }}, and keep the rest. A
+	// template with a body replaces the default's body, and can still include the blocks, ex. {{ template "config" . }}
+	Template string
+}
+
+// Generator generates circuit wrappers in memory. Writing them is left to the caller.
+type Generator struct {
+	opts Options
+}
+
+// New creates a Generator with the options
+func New(opts Options) *Generator {
+	return &Generator{opts: opts}
+}
+
+// Wrapper is the generated circuit wrapper of a target
+type Wrapper struct {
+	// The normalized target the wrapper was generated for
+	Target Target
+
+	// The formatted source
+	Source []byte
+
+	// The parsed type the wrapper was generated from, with its wrapped and skipped methods
+	Type TypeMetadata
+
+	// The imports of the source, starting with the imports added by the template. Unused imports are listed even if
+	// goimports removes them
+	Imports []Import
+}
+
+// WrappedMethods returns the methods wrapped by circuits
+func (w Wrapper) WrappedMethods() []Method {
+	return w.Type.WrappedMethods()
+}
+
+// SkippedMethods returns the methods passed through to the embedded type. Their SkipReason tells why.
+func (w Wrapper) SkippedMethods() []Method {
+	var methods []Method
+	for _, m := range w.Type.Methods {
+		if !m.IsWrappingSupported() {
+			methods = append(methods, m)
+		}
+	}
+	return methods
+}
+
+// Generate generates the circuit wrapper of each target. Targets are normalized, and targets with a name regex are
+// expanded to a wrapper per matching type, so the wrappers hold the targets they were generated for. The packages of all
+// targets are loaded in a single pass.
+func (g *Generator) Generate(targets ...Target) ([]Wrapper, error) {
+	normalized := make([]Target, len(targets))
+	for i, t := range targets {
+		if err := t.Normalize(); err != nil {
+			if len(targets) > 1 {
+				return nil, fmt.Errorf("target %d: %v", i+1, err)
+			}
+			return nil, err
+		}
+		normalized[i] = t
+	}
+
+	tmpl, err := g.template()
+	if err != nil {
+		return nil, err
+	}
+
+	return g.render(tmpl, normalized)
+}
+
+// render generates the circuit wrapper of each normalized target with the template. The packages of all targets are
+// loaded in a single pass, and targets with a name regex are expanded with the loaded packages.
+func (g *Generator) render(tmpl *template.Template, targets []Target) ([]Wrapper, error) {
+	// context is loaded alongside the packages so its context.Context is the same type referenced by the packages
+	patterns := []string{"context"}
+	seen := map[string]bool{}
+	for _, t := range targets {
+		if !seen[t.Pkg] {
+			seen[t.Pkg] = true
+			patterns = append(patterns, t.Pkg)
+		}
+	}
+
+	s := time.Now()
+	pkgs, err := loadPackages(patterns...)
+	if err != nil {
+		return nil, err
+	}
+	g.log("loadPackages took %v", time.Since(s))
+
+	err = firstPackagesError(pkgs)
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err = expandNameRegexTargets(targets, pkgs)
+	if err != nil {
+		return nil, err
+	}
+
+	contextType, err := lookupContextType(pkgs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolving the output package path runs the go tool, so it's done once per output directory
+	outPkgPaths := map[string]string{}
+
+	wrappers := make([]Wrapper, 0, len(targets))
+	for _, t := range targets {
+		out := t.Out
+		if out == StdoutPath {
+			// The wrapper written to stdout is in the package of the working directory
+			out = "."
+		}
+
+		dir := filepath.Dir(out)
+		outPkgPath, ok := outPkgPaths[dir]
+		if !ok {
+			s = time.Now()
+			outPkgPath, err = resolvePackagePath(out)
+			if err != nil {
+				return nil, err
+			}
+			g.log("resolvePackagePath took %v", time.Since(s))
+			outPkgPaths[dir] = outPkgPath
+		}
+
+		wrapper, err := g.renderTarget(tmpl, t, pkgs, contextType, outPkgPath)
+		if err != nil {
+			if len(targets) > 1 {
+				return nil, fmt.Errorf("%s: %v", t.Alias, err)
+			}
+			return nil, err
+		}
+		wrappers = append(wrappers, wrapper)
+	}
+
+	return wrappers, nil
+}
+
+// renderTarget generates the circuit wrapper of a normalized target from the loaded packages
+func (g *Generator) renderTarget(tmpl *template.Template, t Target, pkgs []*packages.Package, contextType types.Type, outPkgPath string) (Wrapper, error) {
+	pkg := lookupPackage(pkgs, t.Pkg)
+	if pkg == nil {
+		return Wrapper{}, fmt.Errorf("could not find loaded package %s", t.Pkg)
+	}
+
+	obj := pkg.Types.Scope().Lookup(t.Name)
+	if obj == nil {
+		return Wrapper{}, errors.New("could not lookup name")
+	}
+
+	typ := obj.Type()
+	if typ == nil {
+		return Wrapper{}, errors.New("object is not a type")
+	}
+
+	filter, err := newMethodFilter(t.Include, t.Exclude)
+	if err != nil {
+		return Wrapper{}, err
+	}
+
+	hedge, err := parseNamePatterns(t.Hedge)
+	if err != nil {
+		return Wrapper{}, err
+	}
+
+	if t.Instantiate != "" {
+		typ, err = instantiateType(pkg.Types, t.Instantiate)
+		if err != nil {
+			return Wrapper{}, err
+		}
+	}
+
+	outPkgName := filepath.Base(outPkgPath)
+
+	// Imports added by the template. Referenced packages colliding with these names are aliased even if the template
+	// doesn't need the import
+	reserved := map[string]string{
+		"context": "context",
+		"errors":  "errors",
+		"fmt":     "fmt",
+		"rand":    "math/rand",
+		"debug":   "runtime/debug",
+		"sync":    "sync",
+		"time":    "time",
+		"circuit": "github.com/cep21/circuit" + circuitVersionSuffix(t.MajorVersion),
+	}
+	for _, name := range templateLocals {
+		reserved[name] = ""
+	}
+
+	s := time.Now()
+	typeMeta, err := parseType(typ, parseConfig{
+		outPkgPath:         outPkgPath,
+		reservedImports:    reserved,
+		contextType:        contextType,
+		contextAccessor:    t.ContextAccessor,
+		wrapWithoutContext: t.WrapWithoutContext,
+		directives:         methodDirectives(pkg.Syntax),
+		methodFilter:       filter,
+		hedge:              hedge,
+		order:              t.Order,
+		fset:               pkg.Fset,
+	})
+	if err != nil {
+		return Wrapper{}, err
+	}
+	g.log("parseType took %v", time.Since(s))
+
+	for _, m := range typeMeta.Methods {
+		if !m.IsWrappingSupported() {
+			g.log("not wrapping method %s: %s", m.Name, m.SkipReason)
+		}
+	}
+
+	templateCtx := TemplateContext{
+		PackageName:   outPkgName,
+		VersionSuffix: circuitVersionSuffix(t.MajorVersion),
+		TypeMetadata:  typeMeta,
+		Alias:         t.Alias,
+	}
+
+	s = time.Now()
+	var b bytes.Buffer
+	err = tmpl.Execute(&b, &templateCtx)
+	if err != nil {
+		return Wrapper{}, fmt.Errorf("rendering circuit wrapper: %v", err)
+	}
+	g.log("executing circuit wrapper template took %v", time.Since(s))
+
+	s = time.Now()
+	var src []byte
+	if g.opts.Goimports {
+		src, err = imports.Process("<gen>", b.Bytes(), nil)
+	} else {
+		src, err = format.Source(b.Bytes())
+	}
+	if err != nil {
+		return Wrapper{}, fmt.Errorf("formatting rendered circuit wrapper: %v", err)
+	}
+	g.log("formatting code took %v", time.Since(s))
+
+	importNames := templateImportNames
+	if len(typeMeta.WrappedMethods()) == 0 {
+		importNames = passThroughImportNames
+	}
+	imps := make([]Import, 0, len(importNames)+len(typeMeta.Imports))
+	for _, name := range importNames {
+		imps = append(imps, Import{Path: reserved[name]})
+	}
+	imps = append(imps, typeMeta.Imports...)
+
+	return Wrapper{Target: t, Source: src, Type: typeMeta, Imports: imps}, nil
+}
+
+// template returns the wrapper template, parsing the custom template of the options over the default
+func (g *Generator) template() (*template.Template, error) {
+	if g.opts.Template == "" {
+		return circuitWrapperTemplate, nil
+	}
+
+	tmpl, err := circuitWrapperTemplate.Clone()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tmpl.Parse(g.opts.Template); err != nil {
+		return nil, fmt.Errorf("parsing custom template: %v", err)
+	}
+
+	return tmpl, nil
+}
+
+// templateImportNames are the names of the imports added by the template, in the order they're imported
+var templateImportNames = []string{"context", "errors", "fmt", "rand", "debug", "sync", "time", "circuit"}
+
+// passThroughImportNames are the names of the imports added by the template if no method is wrapped
+var passThroughImportNames = []string{"context", "circuit"}
+
+func (g *Generator) log(msg string, args ...interface{}) {
+	if g.opts.Logf != nil {
+		g.opts.Logf(msg, args...)
+	}
+}
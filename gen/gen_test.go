@@ -0,0 +1,208 @@
+// Copyright 2019 Twitch Interactive, Inc.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the License is
+// located at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// or in the "license" file accompanying this file. This file is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package gen
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files in testdata/golden")
+
+// TestGoldenShapes renders a wrapper for every shape in testdata/shapes and compares it with its golden file.
+// goimports is disabled to catch any import bugs. Run `go test -run TestGoldenShapes -update` to regenerate the
+// golden files.
+func TestGoldenShapes(t *testing.T) {
+	shapes := []string{
+		"ChanShape",
+		"ArrayShape",
+		"FuncShape",
+		"StructShape",
+		"InterfaceShape",
+		"PassThroughShape",
+		"TypeParamShape",
+		"CollisionShape",
+		"ContextShape",
+		"HedgeShape",
+		"DirectiveShape",
+		"OrderShape",
+	}
+
+	for _, shape := range shapes {
+		shape := shape
+		t.Run(shape, func(t *testing.T) {
+			golden := filepath.Join("testdata", "golden", strings.ToLower(shape)+".golden")
+
+			wrappers, err := New(Options{}).Generate(Target{
+				Pkg:          "./testdata/shapes",
+				Name:         shape,
+				Alias:        shape,
+				Out:          filepath.Join("testdata", "golden", strings.ToLower(shape)+".gen.go"),
+				MajorVersion: 3,
+			})
+			if err != nil {
+				t.Fatalf("rendering %s: %v", shape, err)
+			}
+			src := wrappers[0].Source
+
+			if *updateGolden {
+				if err := ioutil.WriteFile(golden, src, 0600); err != nil {
+					t.Fatalf("updating golden file: %v", err)
+				}
+				return
+			}
+
+			want, err := ioutil.ReadFile(golden) // #nosec G304 test fixture path
+			if err != nil {
+				t.Fatalf("reading golden file: %v", err)
+			}
+			if !bytes.Equal(want, src) {
+				t.Errorf("%s does not match the rendered wrapper. Run `go test -run TestGoldenShapes -update` if the change is expected\ngot:\n%s", golden, src)
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	wrappers, err := New(Options{}).Generate(Target{
+		Pkg:     "./testdata/shapes",
+		Name:    "ChanShape",
+		Out:     "wrappers",
+		Exclude: []string{"Send"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wrappers) != 1 {
+		t.Fatalf("got %d wrappers, want 1", len(wrappers))
+	}
+	w := wrappers[0]
+
+	// The target is normalized
+	if w.Target.Alias != "ChanShape" || w.Target.Out != filepath.Join("wrappers", "chanshape.gen.go") {
+		t.Errorf("got target %+v, want a normalized target", w.Target)
+	}
+	if !bytes.Contains(w.Source, []byte("func NewCircuitWrapperChanShape(")) {
+		t.Errorf("got source without the constructor:\n%s", w.Source)
+	}
+
+	if wrapped := w.WrappedMethods(); len(wrapped) != 1 || wrapped[0].Name != "Subscribe" {
+		t.Errorf("got wrapped methods %+v, want Subscribe", wrapped)
+	}
+	if skipped := w.SkippedMethods(); len(skipped) != 1 || skipped[0].SkipReason != "excluded by method filters" {
+		t.Errorf("got skipped methods %+v, want Send", skipped)
+	}
+
+	// Excluded methods don't add imports
+	var imports []string
+	for _, imp := range w.Imports {
+		imports = append(imports, imp.Path)
+	}
+	want := []string{
+		"context",
+		"errors",
+		"fmt",
+		"math/rand",
+		"runtime/debug",
+		"sync",
+		"time",
+		"github.com/cep21/circuit",
+		"github.com/twitchtv/circuitgen/gen/testdata/shapes",
+		"github.com/twitchtv/circuitgen/internal/circuitgentest/model",
+	}
+	if !reflect.DeepEqual(imports, want) {
+		t.Errorf("got imports %v, want %v", imports, want)
+	}
+}
+
+func TestGenerateCustomTemplate(t *testing.T) {
+	shape := Target{Pkg: "./testdata/shapes", Name: "ChanShape", Out: "wrappers", MajorVersion: 3}
+
+	t.Run("redefined method body", func(t *testing.T) {
+		g := New(Options{Template: `
+{{ define "methodBody" -}}
+	fmt.Println({{ quote .Method.Name }}, {{ join .Method.ParamNames ", " }})
+	{{ template "circuitMethodBody" . }}
+{{- end }}
+`})
+		wrappers, err := g.Generate(shape)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		src := string(wrappers[0].Source)
+		for _, want := range []string{
+			"fmt.Println(\"Subscribe\", ctx, p1)\n\tcallerCtx := ctx\n",
+			"fmt.Println(\"Send\", ctx, p1)\n\tcallerCtx := ctx\n",
+			"type CircuitWrapperChanShapeConfig struct",
+		} {
+			if !strings.Contains(src, want) {
+				t.Errorf("expected the wrapper to contain %q, got:\n%s", want, src)
+			}
+		}
+	})
+
+	t.Run("replaced body", func(t *testing.T) {
+		g := New(Options{Template: `package {{ .PackageName }}
+{{ template "imports" . }}
+{{ range .WrappedMethods }}
+// {{ lower .Name }}
+{{- end }}
+`})
+		wrappers, err := g.Generate(shape)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		src := string(wrappers[0].Source)
+		if !strings.Contains(src, "\"github.com/cep21/circuit/v3\"") || !strings.Contains(src, "// send\n// subscribe\n") {
+			t.Errorf("expected the imports and wrapped methods, got:\n%s", src)
+		}
+		if strings.Contains(src, "CircuitWrapperChanShapeConfig") {
+			t.Errorf("expected the default body to be replaced, got:\n%s", src)
+		}
+	})
+
+	t.Run("invalid template", func(t *testing.T) {
+		if _, err := New(Options{Template: `{{ define "methodBody" }}`}).Generate(shape); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
+
+func TestGeneratePassThroughImports(t *testing.T) {
+	wrappers, err := New(Options{}).Generate(Target{
+		Pkg:  "./testdata/shapes",
+		Name: "PassThroughShape",
+		Out:  "wrappers",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Wrappers without wrapped methods only import context and circuit besides the referenced packages
+	var imports []string
+	for _, imp := range wrappers[0].Imports {
+		imports = append(imports, imp.Path)
+	}
+	want := []string{"context", "github.com/cep21/circuit", "github.com/twitchtv/circuitgen/gen/testdata/shapes"}
+	if !reflect.DeepEqual(imports, want) {
+		t.Errorf("got imports %v, want %v", imports, want)
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright 2019 Twitch Interactive, Inc.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the License is
+// located at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// or in the "license" file accompanying this file. This file is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package gen
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"sort"
+)
+
+// Orders of the methods in generated wrappers
+const (
+	// Methods are sorted by name
+	orderAlpha = "alpha"
+
+	// Methods follow their declaration order in the source files, with the methods of embedded interfaces in place of
+	// the embedding
+	orderSource = "source"
+)
+
+// sortMethods sorts the method set of the type in the order. Methods whose source can't be found, ex. of types loaded
+// without source files, follow the others in declaration order.
+func sortMethods(t types.Type, mset []*types.Selection, order string, fset *token.FileSet) {
+	sort.SliceStable(mset, func(i, j int) bool {
+		return mset[i].Obj().Name() < mset[j].Obj().Name()
+	})
+	if order != orderSource {
+		return
+	}
+
+	// Methods of structs are declared with receivers, possibly across files of the package
+	sort.SliceStable(mset, func(i, j int) bool {
+		return positionLess(fset.Position(mset[i].Obj().Pos()), fset.Position(mset[j].Obj().Pos()))
+	})
+
+	named, ok := t.(*types.Named)
+	if !ok || !types.IsInterface(t) {
+		return
+	}
+
+	o := &sourceOrder{fset: fset, files: map[string]*ast.File{}, ranks: map[string]int{}}
+	o.addInterface(named)
+	sort.SliceStable(mset, func(i, j int) bool {
+		ri, oki := o.ranks[mset[i].Obj().Name()]
+		rj, okj := o.ranks[mset[j].Obj().Name()]
+		if oki && okj {
+			return ri < rj
+		}
+		return oki && !okj
+	})
+}
+
+// positionLess returns whether the position a is before b, comparing file names first.
+func positionLess(a, b token.Position) bool {
+	if a.Filename != b.Filename {
+		return a.Filename < b.Filename
+	}
+	return a.Offset < b.Offset
+}
+
+// sourceOrder ranks the methods of interfaces by walking their declarations in the source files.
+type sourceOrder struct {
+	fset *token.FileSet
+
+	// Parsed source files by name. nil if the file couldn't be parsed
+	files map[string]*ast.File
+
+	// Ranks of the method names in source order
+	ranks map[string]int
+}
+
+// addInterface ranks the methods of the interface declared by the named type that aren't ranked yet, expanding
+// embedded interfaces in place.
+func (o *sourceOrder) addInterface(named *types.Named) {
+	iface, ok := named.Underlying().(*types.Interface)
+	if !ok {
+		return
+	}
+
+	spec := o.lookupInterface(named.Obj())
+	if spec == nil {
+		// Without the source, embedded methods follow the explicit ones
+		for i := 0; i < iface.NumExplicitMethods(); i++ {
+			o.add(iface.ExplicitMethod(i).Name())
+		}
+		for i := 0; i < iface.NumEmbeddeds(); i++ {
+			if embedded, ok := iface.EmbeddedType(i).(*types.Named); ok {
+				o.addInterface(embedded)
+			}
+		}
+		return
+	}
+
+	for _, field := range spec.Methods.List {
+		for _, name := range field.Names {
+			o.add(name.Name)
+		}
+		if len(field.Names) == 0 {
+			if embedded := embeddedType(iface, field.Type); embedded != nil {
+				o.addInterface(embedded)
+			}
+		}
+	}
+}
+
+// add ranks the method name after all ranked names if it isn't ranked yet. Interfaces can embed the same method
+// more than once.
+func (o *sourceOrder) add(name string) {
+	if _, ok := o.ranks[name]; !ok {
+		o.ranks[name] = len(o.ranks)
+	}
+}
+
+// lookupInterface returns the syntax of the interface declared by the type name, or nil if its source can't be found.
+func (o *sourceOrder) lookupInterface(obj *types.TypeName) *ast.InterfaceType {
+	filename := o.fset.Position(obj.Pos()).Filename
+	if filename == "" {
+		return nil
+	}
+
+	f, ok := o.files[filename]
+	if !ok {
+		// Types may be loaded from export data, which only has positions, so parse the file again
+		var err error
+		f, err = parser.ParseFile(token.NewFileSet(), filename, nil, parser.SkipObjectResolution)
+		if err != nil {
+			// Treated like a type without source
+			f = nil
+		}
+		o.files[filename] = f
+	}
+	if f == nil {
+		return nil
+	}
+
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			ts, ok := spec.(*ast.TypeSpec)
+			if !ok || ts.Name.Name != obj.Name() {
+				continue
+			}
+			if iface, ok := ts.Type.(*ast.InterfaceType); ok {
+				return iface
+			}
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// embeddedType returns the named interface embedded by the expression in the interface, or nil if it isn't one. ex. the
+// io.Reader type for the expression "io.Reader"
+func embeddedType(iface *types.Interface, expr ast.Expr) *types.Named {
+	// Instantiated generic interfaces. ex. "Getter[K, V]"
+	switch e := expr.(type) {
+	case *ast.IndexExpr:
+		expr = e.X
+	case *ast.IndexListExpr:
+		expr = e.X
+	}
+
+	var pkgName, name string
+	switch e := expr.(type) {
+	case *ast.Ident:
+		name = e.Name
+	case *ast.SelectorExpr:
+		name = e.Sel.Name
+		if x, ok := e.X.(*ast.Ident); ok {
+			pkgName = x.Name
+		}
+	default:
+		// Type set elements of constraints, ex. "~int | string"
+		return nil
+	}
+
+	var match *types.Named
+	for i := 0; i < iface.NumEmbeddeds(); i++ {
+		embedded, ok := iface.EmbeddedType(i).(*types.Named)
+		if !ok || embedded.Obj().Name() != name {
+			continue
+		}
+		// Prefer the package of the qualifier, which is usually the package name unless the import is aliased
+		if match == nil || (embedded.Obj().Pkg() != nil && embedded.Obj().Pkg().Name() == pkgName) {
+			match = embedded
+		}
+	}
+
+	return match
+}
@@ -0,0 +1,53 @@
+// Copyright 2019 Twitch Interactive, Inc.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the License is
+// located at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// or in the "license" file accompanying this file. This file is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package gen
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSortMethods(t *testing.T) {
+	pkgs, err := loadPackages("./testdata/shapes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := pkgs[0]
+
+	tests := []struct {
+		name  string
+		order string
+		want  []string
+	}{
+		{"OrderShape", orderAlpha, []string{"Close", "Delete", "Exists", "Read", "Write"}},
+		{"OrderShape", orderSource, []string{"Write", "Read", "Exists", "Close", "Delete"}},
+		{"OrderStruct", orderAlpha, []string{"Delete", "Write"}},
+		{"OrderStruct", orderSource, []string{"Write", "Delete"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+" "+tt.order, func(t *testing.T) {
+			typ := pkg.Types.Scope().Lookup(tt.name).Type()
+			mset := methodSet(typ)
+			sortMethods(typ, mset, tt.order, pkg.Fset)
+
+			var got []string
+			for _, m := range mset {
+				got = append(got, m.Obj().Name())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,870 @@
+// Copyright 2019 Twitch Interactive, Inc.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the License is
+// located at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// or in the "license" file accompanying this file. This file is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package gen
+
+import (
+	"errors"
+	"fmt"
+	"go/build"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/go/packages" // latest loader that supports modules
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+func resolvePackagePath(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	conf := &packages.Config{
+		Mode: packages.NeedName,
+	}
+
+	if strings.HasSuffix(path, ".go") {
+		path = filepath.Dir(path)
+	}
+
+	pkgs, err := packages.Load(conf, path)
+	if err != nil {
+		return "", err
+	}
+
+	// We don't check pkgs[0].Errors because the package may not exist
+	return pkgs[0].PkgPath, nil
+}
+
+func loadPackages(pkgPaths ...string) ([]*packages.Package, error) {
+	conf := &packages.Config{
+		Mode: packages.NeedTypes | packages.NeedTypesSizes | packages.NeedImports |
+			packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles | packages.NeedSyntax,
+	}
+
+	pkgs, err := packages.Load(conf, pkgPaths...)
+	if err != nil {
+		return nil, fmt.Errorf("loading packages: %v", err)
+	}
+
+	return pkgs, nil
+}
+
+// lookupPackage returns the loaded package matching the pattern, which is either a package path or a directory.
+func lookupPackage(pkgs []*packages.Package, pattern string) *packages.Package {
+	dir, err := filepath.Abs(pattern)
+	if err != nil {
+		dir = ""
+	}
+
+	for _, pkg := range pkgs {
+		if pkg.PkgPath == pattern || stripVendor(pkg.PkgPath) == stripVendor(pattern) {
+			return pkg
+		}
+		if len(pkg.GoFiles) > 0 && filepath.Dir(pkg.GoFiles[0]) == dir {
+			return pkg
+		}
+	}
+
+	return nil
+}
+
+// matchPackages returns the loaded packages matching the pattern, which is either a package path or a directory. Like
+// the patterns of the go tool, "..." matches any string, and a trailing "/..." also matches the path without it. The
+// wildcard doesn't match vendor and testdata directories unless the pattern names them.
+func matchPackages(pkgs []*packages.Package, pattern string) []*packages.Package {
+	if !strings.Contains(pattern, "...") {
+		if pkg := lookupPackage(pkgs, pattern); pkg != nil {
+			return []*packages.Package{pkg}
+		}
+		return nil
+	}
+
+	local := build.IsLocalImport(pattern) || filepath.IsAbs(pattern)
+	if local {
+		dir, err := filepath.Abs(pattern)
+		if err != nil {
+			return nil
+		}
+		pattern = filepath.ToSlash(dir)
+	}
+
+	expr := strings.ReplaceAll(regexp.QuoteMeta(pattern), `\.\.\.`, `.*`)
+	if strings.HasSuffix(expr, `/.*`) {
+		expr = strings.TrimSuffix(expr, `/.*`) + `(/.*)?`
+	}
+	re := regexp.MustCompile("^" + expr + "$")
+
+	var matched []*packages.Package
+	for _, pkg := range pkgs {
+		path := pkg.PkgPath
+		if local {
+			if len(pkg.GoFiles) == 0 {
+				continue
+			}
+			path = filepath.ToSlash(filepath.Dir(pkg.GoFiles[0]))
+		}
+		if !re.MatchString(path) ||
+			hasPathElement(path, "vendor") && !hasPathElement(pattern, "vendor") ||
+			hasPathElement(path, "testdata") && !hasPathElement(pattern, "testdata") {
+			continue
+		}
+		matched = append(matched, pkg)
+	}
+
+	return matched
+}
+
+func hasPathElement(path, elem string) bool {
+	return strings.Contains("/"+path+"/", "/"+elem+"/")
+}
+
+// lookupContextType returns the context.Context type from the loaded packages.
+func lookupContextType(pkgs []*packages.Package) (types.Type, error) {
+	pkg := lookupPackage(pkgs, "context")
+	if pkg == nil || pkg.Types == nil {
+		return nil, errors.New("context package is not loaded")
+	}
+
+	obj := pkg.Types.Scope().Lookup("Context")
+	if obj == nil {
+		return nil, errors.New("could not lookup context.Context")
+	}
+
+	return obj.Type(), nil
+}
+
+func firstPackagesError(pkgs []*packages.Package) error {
+	var err error
+
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		for _, pkgErr := range pkg.Errors {
+			if err == nil {
+				err = fmt.Errorf("loading package %s: %v", pkg.PkgPath, pkgErr)
+			}
+		}
+	})
+
+	return err
+}
+
+// parseConfig configures parsing a type for generating its wrapper
+type parseConfig struct {
+	// Path of the package the wrapper is generated in. Types in this package are not qualified
+	outPkgPath string
+
+	// Maps the names reserved by the template to the paths of its imports. Names of local variables map to an empty path
+	reservedImports map[string]string
+
+	// The context.Context type from the same load as the parsed type, used to detect context params
+	contextType types.Type
+
+	// Optional accessor expression resolving a context.Context from a param. ex. ".Context()"
+	contextAccessor string
+
+	// Whether to wrap methods without a context param with the base context
+	wrapWithoutContext bool
+
+	// Directives of the methods declared in the loaded syntax, keyed by the position of the method name
+	directives map[token.Pos][]directive
+
+	// Selects the methods wrapped by circuits by name. Excluded methods pass through the embedded type
+	methodFilter methodFilter
+
+	// Patterns of the idempotent methods whose calls can be hedged
+	hedge []namePattern
+
+	// Order of the methods in the wrapper. "alpha" or "source"
+	order string
+
+	// File set of the loaded package, resolving the positions of the methods for ordering by source
+	fset *token.FileSet
+}
+
+// Parse the type for its type info, imports, and methods.
+func parseType(t types.Type, conf parseConfig) (TypeMetadata, error) {
+	mset := methodSet(t)
+	if len(mset) == 0 {
+		return TypeMetadata{}, fmt.Errorf("empty methodset. %v has no exported methods", t)
+	}
+	sortMethods(t, mset, conf.order, conf.fset)
+
+	// Decide which methods are wrapped first, so only the packages referenced by generated code are imported
+	type methodParse struct {
+		sig         *types.Signature
+		ctxIndex    int
+		ctxAccessor string
+		skipReason  string
+		hedged      bool
+		hedgeDelay  time.Duration
+		timeout     time.Duration
+	}
+	var err error
+	parses := make([]methodParse, 0, len(mset))
+	wrapped := make([]*types.Selection, 0, len(mset))
+	for _, m := range mset {
+		sig, ok := m.Type().(*types.Signature)
+		if !ok {
+			return TypeMetadata{}, fmt.Errorf("method %s is not a signature", m.String())
+		}
+
+		ds := conf.directives[m.Obj().Pos()]
+		wrapWithoutContext := conf.wrapWithoutContext || hasDirective(ds, directiveWrapWithoutContext)
+
+		ctxIndex, ctxAccessor := parseContextParam(sig.Params(), conf)
+		skipReason := wrappingSkipReason(sig, ctxIndex, wrapWithoutContext)
+		if skipReason == "" && hasDirective(ds, directiveSkip) {
+			skipReason = "skipped by directive"
+		}
+		if skipReason == "" && !conf.methodFilter.matches(m.Obj().Name()) {
+			skipReason = "excluded by method filters"
+		}
+		if skipReason == "" {
+			wrapped = append(wrapped, m)
+		}
+
+		p := methodParse{sig: sig, ctxIndex: ctxIndex, ctxAccessor: ctxAccessor, skipReason: skipReason}
+		if skipReason == "" {
+			p.hedged, p.hedgeDelay, err = parseHedge(m.Obj().Name(), ds, ctxIndex, ctxAccessor, conf.hedge)
+			if err != nil {
+				return TypeMetadata{}, err
+			}
+			p.timeout, err = parseTimeout(m.Obj().Name(), ds)
+			if err != nil {
+				return TypeMetadata{}, err
+			}
+		}
+		parses = append(parses, p)
+	}
+
+	// Get all the imports
+	imports, err := parseImports(t, wrapped, conf.outPkgPath, conf.reservedImports)
+	if err != nil {
+		return TypeMetadata{}, err
+	}
+	qf := importQualifier(conf.outPkgPath, imports)
+
+	methods := make([]Method, 0, len(mset))
+
+	// For each method of the interface, get the name, params, results, and
+	// other info needed to generate a wrapper.
+	for i, m := range mset {
+		p := parses[i]
+		methods = append(methods, Method{
+			Name:                  m.Obj().Name(),
+			Params:                parseTuple(p.sig.Params(), qf),
+			Results:               parseTuple(p.sig.Results(), qf),
+			Variadic:              p.sig.Variadic(),
+			ContextParamIndex:     p.ctxIndex,
+			ContextAccessor:       p.ctxAccessor,
+			WrappedWithoutContext: p.ctxIndex < 0 && p.skipReason == "",
+			SkipReason:            p.skipReason,
+			Hedged:                p.hedged,
+			HedgeDelay:            p.hedgeDelay,
+			Timeout:               p.timeout,
+		})
+	}
+
+	if err := checkFieldNames(methods); err != nil {
+		return TypeMetadata{}, err
+	}
+
+	tn, ok := t.(*types.Named)
+	if !ok {
+		return TypeMetadata{}, errors.New("not a named type")
+	}
+
+	tm := TypeMetadata{
+		Name:        tn.Obj().Name(),
+		PackageName: tn.Obj().Pkg().Name(),
+		PackagePath: stripVendor(tn.Obj().Pkg().Path()),
+		TypeInfo:    typeInfo(t, qf),
+		TypeParams:  parseTypeParams(tn, qf),
+		Imports:     imports,
+		Methods:     methods,
+	}
+
+	return tm, nil
+}
+
+// wrapperFieldNames are the names of the fields of every wrapper struct
+var wrapperFieldNames = []string{"ShouldSkipError", "IsBadRequest"}
+
+// wrappedFieldNames are the names of the fields of wrapper structs that wrap any method
+var wrappedFieldNames = []string{"RecoverPanics", "Repanic", "CanceledAsBadRequest", "Bypass", "OnBypass"}
+
+// methodFieldPrefixes are prepended to the name of a wrapped method to name its fields in the wrapper struct
+var methodFieldPrefixes = []string{"Circuit", "Fallback", "Retry", "Key", "keyedCircuits", "ShouldSkipError", "IsBadRequest"}
+
+// checkFieldNames returns an error if a field of the wrapper struct has the name of a method. The field would either
+// conflict with the wrapper method or hide the method promoted from the embedded type, ex. the CircuitGet field of
+// method Get and a CircuitGet method.
+func checkFieldNames(methods []Method) error {
+	// Fields by name, mapped to the method they're generated for or empty
+	fields := map[string]string{}
+	for _, name := range wrapperFieldNames {
+		fields[name] = ""
+	}
+	for _, m := range methods {
+		if !m.IsWrappingSupported() {
+			continue
+		}
+		for _, name := range wrappedFieldNames {
+			fields[name] = ""
+		}
+		for _, prefix := range methodFieldPrefixes {
+			fields[prefix+m.Name] = m.Name
+		}
+		if m.Hedged {
+			fields["Hedge"+m.Name] = m.Name
+		}
+		if m.WrappedWithoutContext {
+			fields["BaseContext"] = ""
+		}
+	}
+
+	for _, m := range methods {
+		owner, ok := fields[m.Name]
+		if !ok {
+			continue
+		}
+		if owner == "" {
+			return fmt.Errorf("method %s has the same name as the %s field of the wrapper", m.Name, m.Name)
+		}
+		return fmt.Errorf("method %s has the same name as the %s field generated for method %s", m.Name, m.Name, owner)
+	}
+
+	return nil
+}
+
+// parseHedge returns whether calls of a wrapped method are hedged, either by a directive or by matching a pattern, and
+// the default delay set by the directive. Hedged calls are cancelled through the context param, so the method must
+// take the context itself.
+func parseHedge(name string, ds []directive, ctxIndex int, ctxAccessor string, patterns []namePattern) (bool, time.Duration, error) {
+	d, ok := findDirective(ds, directiveHedge)
+	if !ok && !matchesAny(patterns, name) {
+		return false, 0, nil
+	}
+
+	if ctxIndex < 0 || ctxAccessor != "" {
+		return false, 0, fmt.Errorf("method %s can't be hedged because it does not accept a context.Context param to cancel calls", name)
+	}
+
+	var delay time.Duration
+	if d.value != "" {
+		var err error
+		delay, err = time.ParseDuration(d.value)
+		if err != nil || delay <= 0 {
+			return false, 0, fmt.Errorf("method %s has an invalid hedge delay %q", name, d.value)
+		}
+	}
+
+	return true, delay, nil
+}
+
+// parseTimeout returns the default timeout of the circuit of a wrapped method set by a directive, or zero if unset.
+func parseTimeout(name string, ds []directive) (time.Duration, error) {
+	d, ok := findDirective(ds, directiveTimeout)
+	if !ok {
+		return 0, nil
+	}
+
+	timeout, err := time.ParseDuration(d.value)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("method %s has an invalid timeout %q", name, d.value)
+	}
+
+	return timeout, nil
+}
+
+// wrappingSkipReason returns why a method with the signature can't be wrapped by a circuit, or empty if it can be.
+// ctxIndex is the index of the param the context is taken from, or -1 if there is none, in which case the method is
+// only wrapped if wrapWithoutContext is set.
+func wrappingSkipReason(sig *types.Signature, ctxIndex int, wrapWithoutContext bool) string {
+	if ctxIndex < 0 && !wrapWithoutContext {
+		return "does not accept a context.Context param"
+	}
+
+	results := sig.Results()
+	if results.Len() == 0 || !types.Identical(results.At(results.Len()-1).Type(), types.Universe.Lookup("error").Type()) {
+		return "does not return an error as the last result"
+	}
+
+	return ""
+}
+
+// parseContextParam finds the param a method's context is taken from. This is the first param interchangeable with
+// context.Context, or else the first param the context accessor resolves a context.Context from, in which case the
+// accessor is returned. Returns -1 if there is no such param.
+func parseContextParam(params *types.Tuple, conf parseConfig) (int, string) {
+	for i := 0; i < params.Len(); i++ {
+		if isContext(params.At(i).Type(), conf.contextType) {
+			return i, ""
+		}
+	}
+
+	if conf.contextAccessor == "" {
+		return -1, ""
+	}
+
+	for i := 0; i < params.Len(); i++ {
+		t := resolveAccessor(params.At(i).Type(), conf.contextAccessor)
+		if t != nil && types.AssignableTo(t, conf.contextType) {
+			return i, conf.contextAccessor
+		}
+	}
+
+	return -1, ""
+}
+
+// resolveAccessor returns the type of the accessor expression applied to a value of type t, or nil if it does not
+// resolve. The accessor is a chain of exported fields and methods without params. ex. ".Context()" or ".Meta.Ctx"
+func resolveAccessor(t types.Type, accessor string) types.Type {
+	for _, sel := range strings.Split(strings.TrimPrefix(accessor, "."), ".") {
+		name := strings.TrimSuffix(sel, "()")
+		obj, _, _ := types.LookupFieldOrMethod(t, true, nil, name)
+
+		switch obj := obj.(type) {
+		case *types.Var:
+			if name != sel { // field called like a method
+				return nil
+			}
+			t = obj.Type()
+		case *types.Func:
+			sig, ok := obj.Type().(*types.Signature)
+			if !ok || name == sel || sig.Params().Len() != 0 || sig.Results().Len() != 1 {
+				return nil
+			}
+			t = sig.Results().At(0).Type()
+		default:
+			return nil
+		}
+	}
+
+	return t
+}
+
+// isContext returns whether t is interchangeable with context.Context. The wrapper passes the param to the circuit as
+// a context.Context, and passes the circuit's context.Context back to the embedded method as the param. This holds for
+// context.Context and aliases like aws.Context, but not for types like *gin.Context that only implement it.
+func isContext(t, contextType types.Type) bool {
+	return types.AssignableTo(t, contextType) && types.AssignableTo(contextType, t)
+}
+
+func methodSet(t types.Type) []*types.Selection {
+	var mset []*types.Selection
+	if types.IsInterface(t) {
+		mset = typeutil.IntuitiveMethodSet(t.Underlying(), nil)
+	} else {
+		mset = typeutil.IntuitiveMethodSet(t, nil) // supports structs
+	}
+
+	var exported []*types.Selection
+	for _, m := range mset {
+		if m.Obj().Exported() {
+			exported = append(exported, m)
+		}
+	}
+
+	return exported
+}
+
+// get all the packages referenced by the given type. This walks every type reachable from the type as it would be
+// written in source: named types are not walked into because they are referenced by name, but their type arguments are.
+func resolvePkgs(p types.Type) ([]*types.Package, error) {
+	switch t := p.(type) {
+	case *types.Signature:
+		return resolveAllPkgs(t.Params(), t.Results())
+	case *types.Tuple:
+		return resolveAllPkgs(tupleTypes(t)...)
+	case *types.Pointer:
+		return resolvePkgs(t.Elem())
+	case *types.Map:
+		return resolveAllPkgs(t.Key(), t.Elem())
+	case *types.Slice:
+		return resolvePkgs(t.Elem())
+	case *types.Array:
+		return resolvePkgs(t.Elem())
+	case *types.Chan:
+		return resolvePkgs(t.Elem())
+	case *types.Struct: // ex. struct{ Input *rep.PublishInput }
+		fields := make([]types.Type, 0, t.NumFields())
+		for i := 0; i < t.NumFields(); i++ {
+			fields = append(fields, t.Field(i).Type())
+		}
+		return resolveAllPkgs(fields...)
+	case *types.Interface: // ex. interface{ io.Reader; Result() *model.Result }
+		var ts []types.Type
+		for i := 0; i < t.NumExplicitMethods(); i++ {
+			ts = append(ts, t.ExplicitMethod(i).Type())
+		}
+		for i := 0; i < t.NumEmbeddeds(); i++ {
+			ts = append(ts, t.EmbeddedType(i))
+		}
+		return resolveAllPkgs(ts...)
+	case *types.Union: // ex. ~int | time.Duration in a constraint
+		terms := make([]types.Type, 0, t.Len())
+		for i := 0; i < t.Len(); i++ {
+			terms = append(terms, t.Term(i).Type())
+		}
+		return resolveAllPkgs(terms...)
+	case *types.Named:
+		var r []*types.Package
+		// builtins (e.g. error) have a nil package
+		if pkg := t.Obj().Pkg(); pkg != nil {
+			r = append(r, pkg)
+		}
+		// Type arguments of an instantiated generic type, ex. "Store[string, *model.User]"
+		pkgs, err := resolveAllPkgs(typeListTypes(t.TypeArgs())...)
+		if err != nil {
+			return nil, err
+		}
+		r = append(r, pkgs...)
+		// Constraints of a generic type that is not instantiated, ex. "Store[K fmt.Stringer, V any]"
+		if t.TypeArgs().Len() == 0 {
+			for i := 0; i < t.TypeParams().Len(); i++ {
+				pkgs, err := resolvePkgs(t.TypeParams().At(i).Constraint())
+				if err != nil {
+					return nil, err
+				}
+				r = append(r, pkgs...)
+			}
+		}
+		return r, nil
+	case *types.Alias:
+		var r []*types.Package
+		// The alias is referenced by name (ex. "aws.Context"), so import the package declaring the alias. Universe
+		// aliases (e.g. any) have a nil package
+		if pkg := t.Obj().Pkg(); pkg != nil {
+			r = append(r, pkg)
+		}
+		pkgs, err := resolveAllPkgs(typeListTypes(t.TypeArgs())...)
+		if err != nil {
+			return nil, err
+		}
+		return append(r, pkgs...), nil
+	case *types.TypeParam:
+		// Type parameters are declared by the wrapper, and their constraints are resolved with the generic type.
+		// Constraints are not walked here since they may refer back to the type parameter
+	case *types.Basic:
+		// Break out of the switch and return below
+	default:
+		return nil, fmt.Errorf("resolvePkgs: invalid type: %v", t)
+	}
+
+	return []*types.Package{}, nil
+}
+
+// resolveAllPkgs gets all the packages referenced by the given types
+func resolveAllPkgs(ts ...types.Type) ([]*types.Package, error) {
+	var r []*types.Package
+	for _, t := range ts {
+		pkgs, err := resolvePkgs(t)
+		if err != nil {
+			return nil, err
+		}
+		r = append(r, pkgs...)
+	}
+
+	return r, nil
+}
+
+func tupleTypes(tuple *types.Tuple) []types.Type {
+	ts := make([]types.Type, 0, tuple.Len())
+	for i := 0; i < tuple.Len(); i++ {
+		ts = append(ts, tuple.At(i).Type())
+	}
+
+	return ts
+}
+
+func typeListTypes(list *types.TypeList) []types.Type {
+	ts := make([]types.Type, 0, list.Len())
+	for i := 0; i < list.Len(); i++ {
+		ts = append(ts, list.At(i))
+	}
+
+	return ts
+}
+
+// returns unique imports for the given type. Packages in outPkgPath or in reserved, which maps the names of imports
+// the template always adds to their paths, are not imported. Packages whose names collide are given aliases.
+func parseImports(t types.Type, mset []*types.Selection, outPkgPath string, reserved map[string]string) ([]Import, error) {
+	pkgs := make([]*types.Package, 0, len(mset))
+	for _, m := range mset {
+		mpkgs, err := resolvePkgs(m.Type())
+		if err != nil {
+			return nil, err
+		}
+		pkgs = append(pkgs, mpkgs...)
+	}
+
+	// The type itself may reference other packages through its type arguments or constraints
+	typePkgs, err := resolvePkgs(t)
+	if err != nil {
+		return nil, err
+	}
+	pkgs = append(pkgs, typePkgs...)
+
+	reservedPaths := map[string]bool{}
+	for _, path := range reserved {
+		// Names reserved for local variables have no path
+		if path != "" {
+			reservedPaths[path] = true
+		}
+	}
+
+	var imports []Import
+	names := map[string]string{} // path -> package name
+	addImport := func(path, name string) {
+		if _, ok := names[path]; ok || path == outPkgPath || reservedPaths[path] {
+			return
+		}
+		names[path] = name
+		imports = append(imports, Import{Path: path})
+	}
+
+	// add import for the type first
+	if tn, ok := t.(*types.Named); ok && tn.Obj().Pkg() != nil {
+		addImport(typePackagePath(t), tn.Obj().Pkg().Name())
+	}
+	for _, pkg := range pkgs {
+		addImport(stripVendor(pkg.Path()), pkg.Name())
+	}
+
+	aliases := importAliases(names, reserved)
+	for i := range imports {
+		imports[i].Alias = aliases[imports[i].Path]
+	}
+
+	return imports, nil
+}
+
+// importAliases returns deterministic aliases, keyed by path, for packages whose names collide with another package
+// or with a reserved import name. names maps package paths to package names. A colliding package is aliased by
+// prefixing its name with its parent path element, ex. "github.com/aws/aws-sdk-go-v2/service/s3/types" is "s3types".
+func importAliases(names map[string]string, reserved map[string]string) map[string]string {
+	byName := map[string][]string{}
+	for path, name := range names {
+		byName[name] = append(byName[name], path)
+	}
+
+	taken := map[string]bool{}
+	for name := range reserved {
+		taken[name] = true
+	}
+
+	var colliding []string
+	for name, paths := range byName {
+		if _, ok := reserved[name]; ok || len(paths) > 1 {
+			colliding = append(colliding, paths...)
+		} else {
+			taken[name] = true
+		}
+	}
+	sort.Strings(colliding)
+
+	aliases := map[string]string{}
+	for _, path := range colliding {
+		base := parentPathElement(path) + names[path]
+		alias := base
+		for i := 2; taken[alias]; i++ {
+			alias = base + strconv.Itoa(i)
+		}
+		taken[alias] = true
+		aliases[path] = alias
+	}
+
+	return aliases
+}
+
+var majorVersionElement = regexp.MustCompile(`^v[0-9]+$`)
+
+// parentPathElement returns the path element before the last one as an identifier, skipping major version elements.
+// ex. "s3" for "github.com/aws/aws-sdk-go-v2/service/s3/types"
+func parentPathElement(path string) string {
+	elems := strings.Split(path, "/")
+	for i := len(elems) - 2; i >= 0; i-- {
+		if majorVersionElement.MatchString(elems[i]) {
+			continue
+		}
+
+		var b strings.Builder
+		for _, r := range strings.ToLower(elems[i]) {
+			if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+				b.WriteRune(r)
+			}
+		}
+		return b.String()
+	}
+
+	return ""
+}
+
+// importQualifier qualifies types by the name their package is imported as in the generated code. Types in
+// outPkgPath are not qualified.
+func importQualifier(outPkgPath string, imports []Import) types.Qualifier {
+	aliases := map[string]string{}
+	for _, imp := range imports {
+		if imp.Alias != "" {
+			aliases[imp.Path] = imp.Alias
+		}
+	}
+
+	return func(pkg *types.Package) string {
+		path := stripVendor(pkg.Path())
+		if path == outPkgPath {
+			return ""
+		}
+		if alias, ok := aliases[path]; ok {
+			return alias
+		}
+		return pkg.Name()
+	}
+}
+
+// parseTuple parses a list of variables, like a method's params and results.
+func parseTuple(tuple *types.Tuple, qf types.Qualifier) []TypeInfo {
+	vars := []TypeInfo{}
+
+	for i := 0; i < tuple.Len(); i++ {
+		v := tuple.At(i)
+
+		vars = append(vars, typeInfo(v.Type(), qf))
+	}
+
+	return vars
+}
+
+// parseTypeParams parses the type parameters of a generic type. Instantiated and non-generic types have none.
+func parseTypeParams(tn *types.Named, qf types.Qualifier) []TypeParam {
+	if tn.TypeArgs().Len() > 0 {
+		return nil
+	}
+
+	var params []TypeParam
+	for i := 0; i < tn.TypeParams().Len(); i++ {
+		tp := tn.TypeParams().At(i)
+		params = append(params, TypeParam{
+			Name:       tp.Obj().Name(),
+			Constraint: typeInfo(tp.Constraint(), qf),
+		})
+	}
+
+	return params
+}
+
+func typeInfo(t types.Type, qf types.Qualifier) TypeInfo {
+	return TypeInfo{
+		// The qualifier is set depending on if the type is in the output package
+		// and if its package is aliased
+		Name: typeString(t, qf),
+		NameWithoutQualifier: typeString(t, func(pkg *types.Package) string {
+			return ""
+		}),
+		IsInterface: types.IsInterface(t),
+	}
+}
+
+// typeString is like types.TypeString, except that a generic type that is not instantiated is written with its
+// type parameter names as arguments. ex. "Store[K, V]" instead of "Store[K comparable, V any]"
+func typeString(t types.Type, qf types.Qualifier) string {
+	tn, ok := t.(*types.Named)
+	if !ok || tn.TypeParams().Len() == 0 || tn.TypeArgs().Len() > 0 {
+		return types.TypeString(t, qf)
+	}
+
+	s := tn.Obj().Name()
+	if pkg := tn.Obj().Pkg(); pkg != nil {
+		if q := qf(pkg); q != "" {
+			s = q + "." + s
+		}
+	}
+
+	names := make([]string, tn.TypeParams().Len())
+	for i := range names {
+		names[i] = tn.TypeParams().At(i).Obj().Name()
+	}
+
+	return s + "[" + strings.Join(names, ", ") + "]"
+}
+
+// instantiateType evaluates a type expression such as "Store[string,*model.User]" in the scope of pkg. Qualified
+// identifiers resolve to the packages imported by pkg.
+func instantiateType(pkg *types.Package, expr string) (types.Type, error) {
+	// Type expressions are evaluated without a source position, so there is no file scope holding the imports.
+	// Evaluate in a scratch package whose scope holds both the declarations and the imports of pkg instead.
+	scratch := types.NewPackage(pkg.Path(), pkg.Name())
+	for _, name := range pkg.Scope().Names() {
+		scratch.Scope().Insert(pkg.Scope().Lookup(name))
+	}
+	for _, imp := range pkg.Imports() {
+		if scratch.Scope().Lookup(imp.Name()) == nil {
+			scratch.Scope().Insert(types.NewPkgName(token.NoPos, scratch, imp.Name(), imp))
+		}
+	}
+
+	tv, err := types.Eval(token.NewFileSet(), scratch, token.NoPos, expr)
+	if err != nil {
+		return nil, fmt.Errorf("evaluating %q: %v", expr, err)
+	}
+	if !tv.IsType() {
+		return nil, fmt.Errorf("%q is not a type", expr)
+	}
+
+	tn, ok := tv.Type.(*types.Named)
+	if !ok || tn.TypeArgs().Len() == 0 {
+		return nil, fmt.Errorf("%q is not an instantiated generic type", expr)
+	}
+
+	return tn, nil
+}
+
+// Returns the package path of the given type. The type must be named
+func typePackagePath(t types.Type) string {
+	tn, ok := t.(*types.Named)
+	if !ok {
+		return ""
+	}
+	if tn.Obj() == nil {
+		return ""
+	}
+	if tn.Obj().Pkg() == nil {
+		return ""
+	}
+	return stripVendor(tn.Obj().Pkg().Path())
+}
+
+// stripVendor resolves the given package path by stripping vendor prefixes if needed.
+func stripVendor(path string) string {
+	const vendor = "/vendor/"
+	i := strings.Index(path, vendor)
+	if i > -1 {
+		return path[i+len(vendor):]
+	}
+
+	return path
+}
+
+func circuitVersionSuffix(majorVersion int) string {
+	if majorVersion < 3 {
+		return ""
+	}
+	return "/v" + strconv.Itoa(majorVersion)
+}
@@ -0,0 +1,90 @@
+// Copyright 2019 Twitch Interactive, Inc.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the License is
+// located at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// or in the "license" file accompanying this file. This file is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package gen
+
+import (
+	"testing"
+)
+
+func TestCheckFieldNames(t *testing.T) {
+	const skipped = "does not accept a context.Context param"
+
+	tests := []struct {
+		name    string
+		methods []Method
+		err     string
+	}{
+		{
+			name:    "distinct names",
+			methods: []Method{{Name: "Get"}, {Name: "Put"}},
+		},
+		{
+			name:    "wrapper field",
+			methods: []Method{{Name: "Get"}, {Name: "IsBadRequest", SkipReason: skipped}},
+			err:     "method IsBadRequest has the same name as the IsBadRequest field of the wrapper",
+		},
+		{
+			name:    "method field",
+			methods: []Method{{Name: "CircuitGet"}, {Name: "Get"}},
+			err:     "method CircuitGet has the same name as the CircuitGet field generated for method Get",
+		},
+		{
+			name:    "fallback field",
+			methods: []Method{{Name: "Get"}, {Name: "FallbackGet"}},
+			err:     "method FallbackGet has the same name as the FallbackGet field generated for method Get",
+		},
+		{
+			name:    "key field",
+			methods: []Method{{Name: "KeyGet"}, {Name: "Get"}},
+			err:     "method KeyGet has the same name as the KeyGet field generated for method Get",
+		},
+		{
+			name:    "hedge field",
+			methods: []Method{{Name: "Get", Hedged: true}, {Name: "HedgeGet"}},
+			err:     "method HedgeGet has the same name as the HedgeGet field generated for method Get",
+		},
+		{
+			// Methods that aren't wrapped don't have fields
+			name:    "unwrapped method",
+			methods: []Method{{Name: "CircuitGet"}, {Name: "Get", SkipReason: skipped}},
+		},
+		{
+			name:    "field of wrappers of wrapped methods",
+			methods: []Method{{Name: "Get"}, {Name: "Repanic", SkipReason: skipped}},
+			err:     "method Repanic has the same name as the Repanic field of the wrapper",
+		},
+		{
+			name:    "field of wrappers without wrapped methods",
+			methods: []Method{{Name: "Repanic", SkipReason: skipped}},
+		},
+		{
+			name:    "base context",
+			methods: []Method{{Name: "BaseContext", SkipReason: skipped}, {Name: "Close", WrappedWithoutContext: true}},
+			err:     "method BaseContext has the same name as the BaseContext field of the wrapper",
+		},
+	}
+
+	for _, tt := range tests {
+		err := checkFieldNames(tt.methods)
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("%s: got error %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright 2019 Twitch Interactive, Inc.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the License is
+// located at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// or in the "license" file accompanying this file. This file is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package gen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// ScanOptions configures scanning packages for wrap directives
+type ScanOptions struct {
+	// The version of cep21/circuit to import for directives that don't set it
+	MajorVersion int
+
+	// The order of the methods for directives that don't set it
+	Order string
+}
+
+// Scan loads the packages matching the patterns and returns a normalized target for every wrap directive on their
+// types. Relative output paths are resolved against the directory of the annotated file, which is also the default
+// output path.
+func Scan(patterns []string, opts ScanOptions) ([]Target, error) {
+	pkgs, err := loadPackages(patterns...)
+	if err != nil {
+		return nil, err
+	}
+	if err := firstPackagesError(pkgs); err != nil {
+		return nil, err
+	}
+
+	var targets []Target
+	for _, pkg := range pkgs {
+		for _, f := range pkg.Syntax {
+			for _, decl := range f.Decls {
+				gd, ok := decl.(*ast.GenDecl)
+				if !ok || gd.Tok != token.TYPE {
+					continue
+				}
+
+				for _, spec := range gd.Specs {
+					ts, ok := spec.(*ast.TypeSpec)
+					if !ok {
+						continue
+					}
+					groups := []*ast.CommentGroup{ts.Doc}
+					if !gd.Lparen.IsValid() {
+						// The comment of an ungrouped type declaration belongs to the declaration
+						groups = append(groups, gd.Doc)
+					}
+
+					pos := pkg.Fset.Position(ts.Pos())
+					for _, d := range parseCommentDirectives(groups...) {
+						if d.name != directiveWrap {
+							continue
+						}
+
+						t, err := parseWrapDirective(d.value)
+						if err != nil {
+							return nil, fmt.Errorf("%s: %v", pos, err)
+						}
+						t.Pkg = pkg.PkgPath
+						t.Name = ts.Name.Name
+						t.Out = resolveScannedOut(t.Out, filepath.Dir(pos.Filename))
+						if t.MajorVersion == 0 {
+							t.MajorVersion = opts.MajorVersion
+						}
+						if t.Order == "" {
+							t.Order = opts.Order
+						}
+						if err := t.Normalize(); err != nil {
+							return nil, fmt.Errorf("%s: %v", pos, err)
+						}
+
+						targets = append(targets, t)
+					}
+				}
+			}
+		}
+	}
+
+	return targets, nil
+}
+
+// expandNameRegexTargets replaces every normalized target with a name regex by a normalized target for each exported
+// type with methods matching it in the loaded packages matching the target's pattern. Other targets are kept as is.
+func expandNameRegexTargets(targets []Target, pkgs []*packages.Package) ([]Target, error) {
+	var expanded []Target
+	for _, t := range targets {
+		if t.NameRegex == "" {
+			expanded = append(expanded, t)
+			continue
+		}
+
+		matched, err := matchNameRegex(t, pkgs)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, matched...)
+	}
+
+	// Wrappers of types named alike in different packages would overwrite each other
+	outs := map[string]string{}
+	for _, t := range expanded {
+		if t.Out == StdoutPath {
+			continue
+		}
+
+		typ := t.Pkg + "." + t.Name
+		if other, ok := outs[t.Out]; ok {
+			return nil, fmt.Errorf("%s and %s are both generated to %s. Use a capture group in name-regex to derive distinct aliases", other, typ, t.Out)
+		}
+		outs[t.Out] = typ
+	}
+
+	return expanded, nil
+}
+
+// matchNameRegex returns a normalized target for each exported type with methods matching the name regex of the
+// target in the loaded packages matching its pattern.
+func matchNameRegex(t Target, pkgs []*packages.Package) ([]Target, error) {
+	re := regexp.MustCompile(t.NameRegex) // Validated by normalize
+
+	var targets []Target
+	for _, pkg := range matchPackages(pkgs, t.Pkg) {
+		scope := pkg.Types.Scope()
+		for _, name := range scope.Names() {
+			obj, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || !obj.Exported() || obj.IsAlias() || len(methodSet(obj.Type())) == 0 {
+				continue
+			}
+
+			m := re.FindStringSubmatch(name)
+			if m == nil {
+				continue
+			}
+
+			matched := t
+			matched.NameRegex = ""
+			matched.Pkg = pkg.PkgPath
+			matched.Name = name
+			if len(m) > 1 && m[1] != "" {
+				matched.Alias = m[1]
+			}
+			if err := matched.Normalize(); err != nil {
+				return nil, fmt.Errorf("%s.%s: %v", pkg.PkgPath, name, err)
+			}
+			targets = append(targets, matched)
+		}
+	}
+
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no exported types with methods match name-regex %s in %s", t.NameRegex, t.Pkg)
+	}
+
+	return targets, nil
+}
+
+// resolveScannedOut resolves the output path of a wrap directive against the directory of the annotated file.
+func resolveScannedOut(out, dir string) string {
+	if out == StdoutPath || filepath.IsAbs(out) {
+		return out
+	}
+
+	return filepath.Join(dir, out)
+}
+
+// parseWrapDirective parses the options of a wrap directive into a target. Options are space separated key=value pairs
+// named like the config file fields, ex. "alias=Pubsub out=./wrappers include=Get*". List options can be repeated and
+// the value of boolean options can be omitted.
+func parseWrapDirective(value string) (Target, error) {
+	var t Target
+	for _, opt := range strings.Fields(value) {
+		key, val, hasVal := strings.Cut(opt, "=")
+		if key != "wrap-without-context" && val == "" {
+			return Target{}, fmt.Errorf("wrap option %s requires a value", key)
+		}
+
+		switch key {
+		case "alias":
+			t.Alias = val
+		case "out":
+			t.Out = val
+		case "context-accessor":
+			t.ContextAccessor = val
+		case "wrap-without-context":
+			t.WrapWithoutContext = true
+			if hasVal {
+				b, err := strconv.ParseBool(val)
+				if err != nil {
+					return Target{}, fmt.Errorf("wrap option %s has an invalid value %q", key, val)
+				}
+				t.WrapWithoutContext = b
+			}
+		case "include":
+			t.Include = append(t.Include, val)
+		case "exclude":
+			t.Exclude = append(t.Exclude, val)
+		case "hedge":
+			t.Hedge = append(t.Hedge, val)
+		case "order":
+			t.Order = val
+		case "circuit-major-version":
+			v, err := strconv.Atoi(val)
+			if err != nil {
+				return Target{}, fmt.Errorf("wrap option %s has an invalid value %q", key, val)
+			}
+			t.MajorVersion = v
+		default:
+			return Target{}, fmt.Errorf("unknown wrap option %s", key)
+		}
+	}
+
+	return t, nil
+}
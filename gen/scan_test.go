@@ -0,0 +1,209 @@
+// Copyright 2019 Twitch Interactive, Inc.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the License is
+// located at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// or in the "license" file accompanying this file. This file is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package gen
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseWrapDirective(t *testing.T) {
+	got, err := parseWrapDirective("alias=Pubsub out=./wrappers include=Get* include=/^Put/ exclude=Close hedge=Get* wrap-without-context context-accessor=.Context() order=source circuit-major-version=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Target{
+		Alias:              "Pubsub",
+		Out:                "./wrappers",
+		ContextAccessor:    ".Context()",
+		WrapWithoutContext: true,
+		MajorVersion:       3,
+		Include:            []string{"Get*", "/^Put/"},
+		Exclude:            []string{"Close"},
+		Hedge:              []string{"Get*"},
+		Order:              "source",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	for _, value := range []string{
+		"name=Publisher",
+		"alias",
+		"out=",
+		"wrap-without-context=maybe",
+		"circuit-major-version=three",
+	} {
+		if _, err := parseWrapDirective(value); err == nil {
+			t.Errorf("expected an error for %q", value)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	targets, err := Scan([]string{"./testdata/shapes"}, ScanOptions{MajorVersion: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := filepath.Abs(filepath.Join("testdata", "shapes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Target{{
+		Pkg:          "github.com/twitchtv/circuitgen/gen/testdata/shapes",
+		Name:         "DirectiveShape",
+		Alias:        "Directive",
+		Out:          filepath.Join(dir, "wrappers", "directive.gen.go"),
+		MajorVersion: 3,
+		Exclude:      []string{"Close"},
+		Order:        orderAlpha,
+	}}
+	if !reflect.DeepEqual(targets, want) {
+		t.Fatalf("got %+v, want %+v", targets, want)
+	}
+
+	wrappers, err := New(Options{}).Generate(targets...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wrapped := wrappers[0].WrappedMethods()
+	if len(wrapped) != 1 || wrapped[0].Name != "Get" || wrapped[0].Timeout != 200*time.Millisecond {
+		t.Errorf("got wrapped methods %+v, want Get with a 200ms timeout", wrapped)
+	}
+
+	skipped := map[string]string{}
+	for _, m := range wrappers[0].SkippedMethods() {
+		skipped[m.Name] = m.SkipReason
+	}
+	wantSkipped := map[string]string{"Close": "excluded by method filters", "Put": "skipped by directive"}
+	if !reflect.DeepEqual(skipped, wantSkipped) {
+		t.Errorf("got skipped methods %v, want %v", skipped, wantSkipped)
+	}
+}
+
+func TestExpandNameRegexTargets(t *testing.T) {
+	regexTarget := func(pattern string) Target {
+		t.Helper()
+		rt := Target{Pkg: "./testdata/shapes/...", NameRegex: pattern, Out: "wrappers"}
+		if err := rt.Normalize(); err != nil {
+			t.Fatal(err)
+		}
+		return rt
+	}
+
+	pkgs, err := loadPackages("./testdata/shapes/...")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("aliases from capture groups", func(t *testing.T) {
+		targets, err := expandNameRegexTargets([]Target{regexTarget("^(Chan|Hedge)Shape$")}, pkgs)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []Target{
+			{
+				Pkg:          "github.com/twitchtv/circuitgen/gen/testdata/shapes",
+				Name:         "ChanShape",
+				Alias:        "Chan",
+				Out:          filepath.Join("wrappers", "chan.gen.go"),
+				MajorVersion: 2,
+				Order:        orderAlpha,
+			},
+			{
+				Pkg:          "github.com/twitchtv/circuitgen/gen/testdata/shapes",
+				Name:         "HedgeShape",
+				Alias:        "Hedge",
+				Out:          filepath.Join("wrappers", "hedge.gen.go"),
+				MajorVersion: 2,
+				Order:        orderAlpha,
+			},
+		}
+		if !reflect.DeepEqual(targets, want) {
+			t.Errorf("got %+v, want %+v", targets, want)
+		}
+	})
+
+	t.Run("type names without capture groups", func(t *testing.T) {
+		targets, err := expandNameRegexTargets([]Target{regexTarget("^Chan")}, pkgs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(targets) != 1 || targets[0].Alias != "ChanShape" {
+			t.Errorf("got %+v, want a ChanShape target", targets)
+		}
+	})
+
+	t.Run("colliding aliases", func(t *testing.T) {
+		_, err := expandNameRegexTargets([]Target{regexTarget("^(?:Chan|Hedge)(Shape)$")}, pkgs)
+		if err == nil || !strings.Contains(err.Error(), "are both generated to") {
+			t.Errorf("got error %v, want a collision", err)
+		}
+	})
+
+	t.Run("no matches", func(t *testing.T) {
+		if _, err := expandNameRegexTargets([]Target{regexTarget("^Missing$")}, pkgs); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
+
+func TestMatchPackages(t *testing.T) {
+	pkgs, err := loadPackages("context", "./testdata/shapes/...")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const shapes = "github.com/twitchtv/circuitgen/gen/testdata/shapes"
+	for pattern, want := range map[string][]string{
+		"./testdata/shapes":             {shapes},
+		shapes:                          {shapes},
+		"./testdata/shapes/...":         {shapes, shapes + "/breaker/circuit", shapes + "/dynamodb/types", shapes + "/legacy/context", shapes + "/s3/types"},
+		shapes + "/...":                 {shapes, shapes + "/breaker/circuit", shapes + "/dynamodb/types", shapes + "/legacy/context", shapes + "/s3/types"},
+		"./testdata/shapes/.../types":   {shapes + "/dynamodb/types", shapes + "/s3/types"},
+		"github.com/twitchtv/...":       nil,
+		"./testdata/shapes/missing/...": nil,
+	} {
+		var got []string
+		for _, pkg := range matchPackages(pkgs, pattern) {
+			got = append(got, pkg.PkgPath)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %v, want %v", pattern, got, want)
+		}
+	}
+}
+
+func TestNameRegexTargetValidation(t *testing.T) {
+	for _, rt := range []Target{
+		{Pkg: "./...", NameRegex: "API$", Name: "DynamoDBAPI", Out: "wrappers"},
+		{Pkg: "./...", NameRegex: "API$", Alias: "DynamoDB", Out: "wrappers"},
+		{Pkg: "./...", NameRegex: "API$", Out: "wrappers/dynamodb.gen.go"},
+		{Pkg: "./...", NameRegex: "API$", Out: StdoutPath},
+		{Pkg: "./...", NameRegex: "(", Out: "wrappers"},
+	} {
+		if err := rt.Normalize(); err == nil {
+			t.Errorf("expected an error for %+v", rt)
+		}
+	}
+}
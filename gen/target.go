@@ -0,0 +1,147 @@
+// Copyright 2019 Twitch Interactive, Inc.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the License is
+// located at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// or in the "license" file accompanying this file. This file is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package gen
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// StdoutPath is the output path of a wrapper written to stdout. The wrapper is generated in the package of the working
+// directory.
+const StdoutPath = "-"
+
+// Target configures the circuit wrapper generated for a type. Targets are set with the flags of the circuitgen command,
+// listed in its config file, or scanned from directives.
+type Target struct {
+	// The path to the package. Relative paths in a config file are relative to the config file. A package pattern,
+	// ex. "./...", if NameRegex is set
+	Pkg string `yaml:"pkg"`
+
+	// The name of the type in the package
+	Name string `yaml:"name"`
+
+	// A regular expression matching the names of the types to wrap in the packages, instead of Name. The alias of each
+	// wrapper is the first capture group if any, or the type name
+	NameRegex string `yaml:"name-regex"`
+
+	// A type expression instantiating a generic type, ex. "Store[string,*model.User]". Sets Name if empty
+	Instantiate string `yaml:"instantiate"`
+
+	// The output path. Relative paths in a config file are relative to the config file
+	Out string `yaml:"out"`
+
+	// The name used for the generated wrapper. Defaults to Name
+	Alias string `yaml:"alias"`
+
+	// Accessor expression resolving a context from a param, ex. ".Context()"
+	ContextAccessor string `yaml:"context-accessor"`
+
+	// Whether to wrap methods without a context param with the base context
+	WrapWithoutContext bool `yaml:"wrap-without-context"`
+
+	// The version of cep21/circuit to import
+	MajorVersion int `yaml:"circuit-major-version"`
+
+	// Glob or regex patterns of the methods to wrap. All methods are wrapped if empty
+	Include []string `yaml:"include"`
+
+	// Glob or regex patterns of the methods not to wrap. Excluded methods pass through the embedded type
+	Exclude []string `yaml:"exclude"`
+
+	// Glob or regex patterns of the idempotent methods whose calls can be hedged
+	Hedge []string `yaml:"hedge"`
+
+	// Order of the methods in the wrapper. "alpha" sorts by name, and "source" follows the declaration order in the
+	// source files. Defaults to "alpha"
+	Order string `yaml:"order"`
+}
+
+// contextAccessorPattern matches accessor expressions of fields and methods without params. ex. ".Context()"
+var contextAccessorPattern = regexp.MustCompile(`^(\.[A-Za-z_][A-Za-z0-9_]*(\(\))?)+$`)
+
+// Normalize validates the target and sets defaults
+func (t *Target) Normalize() error {
+	if t.Pkg == "" {
+		return errors.New("pkg is required")
+	}
+	if t.Out == "" {
+		return errors.New("out is required")
+	}
+
+	if t.Instantiate != "" {
+		name := t.Instantiate
+		if i := strings.Index(name, "["); i > -1 {
+			name = strings.TrimSpace(name[:i])
+		}
+		if t.Name != "" && t.Name != name {
+			return fmt.Errorf("name %s does not match the instantiated type %s", t.Name, t.Instantiate)
+		}
+		t.Name = name
+	}
+	if t.NameRegex != "" {
+		if t.Name != "" || t.Alias != "" {
+			return errors.New("name-regex can't be combined with name, instantiate, or alias")
+		}
+		if _, err := regexp.Compile(t.NameRegex); err != nil {
+			return fmt.Errorf("invalid name-regex: %v", err)
+		}
+		if t.Out == StdoutPath || strings.HasSuffix(t.Out, ".go") {
+			return errors.New("name-regex requires out to be a directory")
+		}
+	} else if t.Name == "" {
+		return errors.New("name or instantiate is required")
+	}
+
+	if t.Alias == "" {
+		t.Alias = t.Name
+	}
+
+	if t.ContextAccessor != "" {
+		if !strings.HasPrefix(t.ContextAccessor, ".") {
+			t.ContextAccessor = "." + t.ContextAccessor
+		}
+		if !contextAccessorPattern.MatchString(t.ContextAccessor) {
+			return fmt.Errorf("context accessor %s is not a chain of fields and methods without params", t.ContextAccessor)
+		}
+	}
+
+	if _, err := newMethodFilter(t.Include, t.Exclude); err != nil {
+		return err
+	}
+	if _, err := parseNamePatterns(t.Hedge); err != nil {
+		return err
+	}
+
+	if t.MajorVersion == 0 {
+		t.MajorVersion = 2
+	}
+
+	switch t.Order {
+	case "":
+		t.Order = orderAlpha
+	case orderAlpha, orderSource:
+	default:
+		return fmt.Errorf("order %s is not %s or %s", t.Order, orderAlpha, orderSource)
+	}
+
+	if t.NameRegex == "" && t.Out != StdoutPath && !strings.HasSuffix(t.Out, ".go") {
+		t.Out = filepath.Join(t.Out, strings.ToLower(t.Alias)+".gen.go")
+	}
+
+	return nil
+}
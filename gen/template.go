@@ -0,0 +1,924 @@
+// Copyright 2019 Twitch Interactive, Inc.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the License is
+// located at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// or in the "license" file accompanying this file. This file is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package gen
+
+import (
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// templateFuncs are the functions available to wrapper templates besides the builtin functions of text/template
+var templateFuncs = template.FuncMap{
+	// lower returns the string in lower case. ex. {{ lower .Method.Name }}
+	"lower": strings.ToLower,
+
+	// upper returns the string in upper case
+	"upper": strings.ToUpper,
+
+	// quote returns the string as a double-quoted Go string literal. ex. {{ quote .Method.Name }}
+	"quote": strconv.Quote,
+
+	// join joins the strings with the separator. ex. {{ join .Method.ParamNames ", " }}
+	"join": strings.Join,
+}
+
+// circuitWrapperTemplate is a template for generating a circuit wrapper. Conditional logic should be minimized in the
+// template for readability.
+//
+// The sections of the wrapper are named blocks, so a custom template can redefine any of them and keep the rest:
+// "imports", "config", "retryPolicy", "wrapper", "constructor", "helpers", and "interfaceCheck" are executed with the
+// TemplateContext. "method" generates a wrapped method, and "methodBody" its body, which is "circuitMethodBody" or
+// "hedgedMethodBody". They are executed with a MethodTemplateContext.
+var circuitWrapperTemplate = template.Must(template.Must(template.New("").Funcs(templateFuncs).Parse(`
+// Code ` + `generated by circuitgen tool. DO NOT EDIT
+
+package {{ .PackageName }}
+
+{{ block "imports" . }}import (
+	"context"
+	{{ if .WrappedMethods -}}
+	"errors"
+	"fmt"
+	"math/rand"
+	"runtime/debug"
+	"sync"
+	"time"
+	{{ end -}}
+	"github.com/cep21/circuit{{ .VersionSuffix }}"
+	{{ range .TypeMetadata.Imports -}}
+		{{ if .Alias }}{{ .Alias }} {{ end }}"{{ .Path }}"
+	{{ end -}}
+){{ end }}
+
+{{ block "config" . }}// {{ .WrapperStructName }}Config contains configuration for {{ .WrapperStructName }}. All fields are optional
+type {{ .WrapperStructName }}Config{{ .TypeParamsDeclaration }} struct {
+	// ShouldSkipError determines whether an error should be skipped and have the circuit
+	// track the call as successful. This takes precedence over IsBadRequest
+	ShouldSkipError func(error) bool
+
+	// IsBadRequest is an optional bad request checker. It is useful to not count user errors as faults
+	//
+	// Both checkers receive the error of the embedded method as is, so wrapped errors can be checked with errors.Is
+	// and errors.As
+	IsBadRequest func(error) bool
+
+	{{ if .WrapsWithoutContext -}}
+	// BaseContext returns the context for circuits of methods without a context param. Defaults to context.Background
+	BaseContext func() context.Context
+
+	{{ end -}}
+
+	// Prefix is prepended to all circuit names
+	Prefix string
+
+	// Defaults are used for all created circuits. Per-circuit configs override this
+	Defaults circuit.Config
+
+	{{ if .WrappedMethods -}}
+	// MaxKeys bounds the number of keyed circuits of each method. Calls with new keys use the circuit of the method once
+	// it is reached. Defaults to 1000
+	MaxKeys int
+
+	// Retry is the retry policy of all methods. Calls are attempted once if nil
+	Retry *{{ .WrapperStructName }}RetryPolicy
+
+	// RecoverPanics recovers panics of the embedded methods into a {{ .WrapperStructName }}PanicError, which the
+	// circuit counts as a failure. Panics are not recovered by default
+	RecoverPanics bool
+
+	// Repanic panics again with the {{ .WrapperStructName }}PanicError of a recovered panic after the circuit counted
+	// it, unless a fallback handled it. This has no effect without RecoverPanics
+	Repanic bool
+
+	// CanceledAsBadRequest counts calls failing after the caller's context is done as bad requests, which don't fall
+	// back and aren't retried. Otherwise the circuit counts them as interrupts, unless it ignores interrupts. Either
+	// way they don't count as failures, unlike the circuit's timeouts
+	CanceledAsBadRequest bool
+
+	// Bypass returns whether calls with the context bypass the circuits and call the embedded methods directly, ex.
+	// for admin tools. Bypassed calls aren't tracked by the circuits. Set it to circuitbypass.IsBypassed to bypass
+	// calls with contexts from circuitbypass.WithCircuitBypass. Calls aren't bypassed if nil
+	Bypass func(context.Context) bool
+
+	// OnBypass is called with the method name of every bypassed call if set, ex. to report metrics
+	OnBypass func(method string)
+
+	{{ end -}}
+
+	{{ range $i, $meth := .TypeMetadata.Methods -}}
+		{{ if $meth.IsWrappingSupported -}}
+			// Circuit{{ $meth.Name }} is the configuration used for the {{ $meth.Name }} circuit. This overrides values set by Defaults
+			{{- if $meth.Timeout }}. Its timeout defaults to {{ $meth.Timeout }}{{ end }}
+			Circuit{{ $meth.Name }} circuit.Config
+			// Fallback{{ $meth.Name }} is called with the params of {{ $meth.Name }} and the circuit error when the call fails or
+			// the circuit is open. Its results are returned instead. Bad requests and skipped errors don't fall back
+			Fallback{{ $meth.Name }} {{ $meth.FallbackSignature }}
+			// Retry{{ $meth.Name }} is the retry policy of {{ $meth.Name }}. This overrides Retry
+			Retry{{ $meth.Name }} *{{ $.WrapperStructName }}RetryPolicy
+			// Key{{ $meth.Name }} returns the key of a call of {{ $meth.Name }}. Calls with a non-empty key use a circuit of the key
+			// named Prefix+"{{ $.Alias }}.{{ $meth.Name }}.<key>", created on first use with Circuit{{ $meth.Name }}. Otherwise calls use
+			// Circuit{{ $meth.Name }}
+			Key{{ $meth.Name }} {{ $meth.KeySignature }}
+			// ShouldSkipError{{ $meth.Name }} overrides ShouldSkipError for {{ $meth.Name }}. It receives the params of the call
+			ShouldSkipError{{ $meth.Name }} {{ $meth.ClassifierSignature }}
+			// IsBadRequest{{ $meth.Name }} overrides IsBadRequest for {{ $meth.Name }}. It receives the params of the call
+			IsBadRequest{{ $meth.Name }} {{ $meth.ClassifierSignature }}
+			{{ if $meth.Hedged -}}
+				// Hedge{{ $meth.Name }} is the delay after which a call of {{ $meth.Name }} that hasn't finished is hedged with a second
+				// call. The first to succeed is returned and the other is cancelled. Calls aren't hedged if not positive
+				{{- if $meth.HedgeDelay }}. Defaults to {{ $meth.HedgeDelay }}{{ end }}
+				Hedge{{ $meth.Name }} time.Duration
+			{{ end -}}
+		{{ end -}}
+	{{ end }}
+}{{ end }}
+
+{{ block "retryPolicy" . }}{{ if .WrappedMethods -}}
+// {{ .WrapperStructName }}RetryPolicy configures retrying failed calls of {{ .WrapperStructName }}. Bad requests,
+// skipped errors, open circuits, and recovered panics are never retried
+type {{ .WrapperStructName }}RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts of a call, including the first. Calls are attempted once if less than 2
+	MaxAttempts int
+
+	// InitialBackoff is the backoff before the first retry. It doubles for every retry after
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the backoff between retries if set
+	MaxBackoff time.Duration
+
+	// Jitter is the fraction of each backoff that is randomly subtracted from it, between 0 and 1. 1 is full jitter
+	Jitter float64
+
+	// ShouldRetry determines whether an error is retried. Defaults to retrying all errors
+	ShouldRetry func(error) bool
+
+	// AroundCircuit retries calls around the circuit, so every attempt is tracked by the circuit and has its own
+	// timeout. The fallback is called once the attempts run out. Otherwise calls are retried inside the circuit, which
+	// tracks all attempts as one call bounded by its timeout
+	AroundCircuit bool
+}
+{{ end }}{{ end }}
+
+{{ block "wrapper" . }}// {{ .WrapperStructName }} is a circuit wrapper for {{ .EmbeddedType }}
+type {{ .WrapperStructName }}{{ .TypeParamsDeclaration }} struct {
+	{{ .EmbeddedType }}
+
+	// ShouldSkipError determines whether an error should be skipped and have the circuit
+	// track the call as successful. This takes precedence over IsBadRequest
+	ShouldSkipError func(error) bool
+
+	// IsBadRequest checks whether to count a user error against the circuit. It is recommended to set this
+	IsBadRequest func(error) bool
+
+	{{ if .WrappedMethods -}}
+	// RecoverPanics recovers panics of the embedded methods into a {{ .WrapperStructName }}PanicError
+	RecoverPanics bool
+
+	// Repanic panics again with recovered panics after the circuit counted them
+	Repanic bool
+
+	// CanceledAsBadRequest counts calls failing after the caller's context is done as bad requests
+	CanceledAsBadRequest bool
+
+	// Bypass returns whether calls with the context bypass the circuits
+	Bypass func(context.Context) bool
+
+	// OnBypass is called with the method name of every bypassed call
+	OnBypass func(method string)
+
+	{{ end -}}
+	{{ if .WrapsWithoutContext -}}
+	// BaseContext returns the context for circuits of methods without a context param
+	BaseContext func() context.Context
+
+	{{ end -}}
+
+	{{ range $i, $meth := .TypeMetadata.Methods -}}
+		{{ if $meth.IsWrappingSupported -}}
+			// Circuit{{ $meth.Name }} is the circuit for method {{ $meth.Name }}
+			Circuit{{ $meth.Name }} *circuit.Circuit
+			// Fallback{{ $meth.Name }} is the optional fallback for method {{ $meth.Name }}
+			Fallback{{ $meth.Name }} {{ $meth.FallbackSignature }}
+			// Retry{{ $meth.Name }} is the optional retry policy for method {{ $meth.Name }}
+			Retry{{ $meth.Name }} *{{ $.WrapperStructName }}RetryPolicy
+			// Key{{ $meth.Name }} is the optional key function of the keyed circuits for method {{ $meth.Name }}
+			Key{{ $meth.Name }} {{ $meth.KeySignature }}
+			keyedCircuits{{ $meth.Name }} *{{ $.KeyedCircuitsTypeName }}
+			// ShouldSkipError{{ $meth.Name }} determines whether an error of method {{ $meth.Name }} should be skipped
+			ShouldSkipError{{ $meth.Name }} {{ $meth.ClassifierSignature }}
+			// IsBadRequest{{ $meth.Name }} checks whether to count an error of method {{ $meth.Name }} against the circuit
+			IsBadRequest{{ $meth.Name }} {{ $meth.ClassifierSignature }}
+			{{ if $meth.Hedged -}}
+				// Hedge{{ $meth.Name }} is the delay before hedging calls of method {{ $meth.Name }}. Calls aren't hedged if not positive
+				Hedge{{ $meth.Name }} time.Duration
+			{{ end -}}
+		{{ end -}}
+	{{ end }}
+}{{ end }}
+
+{{ block "constructor" . }}// New{{ .WrapperStructName }} creates a new circuit wrapper and initializes circuits
+func New{{ .WrapperStructName }}{{ .TypeParamsDeclaration }}(
+	manager *circuit.Manager,
+	embedded {{ .EmbeddedType }},
+	conf {{ .WrapperStructName }}Config{{ .TypeArguments }},
+) (*{{ .WrapperStructName }}{{ .TypeArguments }}, error) {
+	if conf.ShouldSkipError == nil {
+		conf.ShouldSkipError = func(err error) bool {
+			return false
+		}
+	}
+
+	if conf.IsBadRequest == nil {
+		conf.IsBadRequest = func(err error) bool {
+			return false
+		}
+	}
+
+	{{ if .WrapsWithoutContext -}}
+	if conf.BaseContext == nil {
+		conf.BaseContext = context.Background
+	}
+	{{ end -}}
+
+	{{ if .WrappedMethods -}}
+	if conf.MaxKeys <= 0 {
+		conf.MaxKeys = 1000
+	}
+
+	{{ end -}}
+	{{ range $i, $meth := .TypeMetadata.Methods -}}
+		{{ if and $meth.IsWrappingSupported $meth.HedgeDelay -}}
+			if conf.Hedge{{ $meth.Name }} == 0 {
+				conf.Hedge{{ $meth.Name }} = {{ $meth.HedgeDelayExpression }}
+			}
+
+		{{ end -}}
+		{{ if and $meth.IsWrappingSupported $meth.Timeout -}}
+			if conf.Circuit{{ $meth.Name }}.Execution.Timeout == 0 {
+				conf.Circuit{{ $meth.Name }}.Execution.Timeout = {{ $meth.TimeoutExpression }}
+			}
+
+		{{ end -}}
+	{{ end -}}
+
+	w := &{{ .WrapperStructName }}{{ .TypeArguments }}{
+		{{ .EmbeddedName }}: embedded,
+		ShouldSkipError: conf.ShouldSkipError,
+		IsBadRequest: conf.IsBadRequest,
+		{{ if .WrappedMethods -}}
+		RecoverPanics: conf.RecoverPanics,
+		Repanic: conf.Repanic,
+		CanceledAsBadRequest: conf.CanceledAsBadRequest,
+		Bypass: conf.Bypass,
+		OnBypass: conf.OnBypass,
+		{{ end -}}
+		{{ if .WrapsWithoutContext -}}
+		BaseContext: conf.BaseContext,
+		{{ end -}}
+		{{ range $i, $meth := .TypeMetadata.Methods -}}
+			{{ if $meth.IsWrappingSupported -}}
+				Fallback{{ $meth.Name }}: conf.Fallback{{ $meth.Name }},
+				Key{{ $meth.Name }}: conf.Key{{ $meth.Name }},
+				keyedCircuits{{ $meth.Name }}: new{{ $.WrapperStructName }}KeyedCircuits(manager, conf.Prefix + "{{ $.Alias }}.{{ $meth.Name }}", conf.MaxKeys, conf.Circuit{{ $meth.Name }}, conf.Defaults),
+				{{ if $meth.Hedged -}}
+					Hedge{{ $meth.Name }}: conf.Hedge{{ $meth.Name }},
+				{{ end -}}
+			{{ end -}}
+		{{ end -}}
+	}
+
+	{{ if .WrappedMethods -}}
+	var err error
+	{{ end -}}
+	{{ range $i, $meth := .TypeMetadata.Methods -}}
+		{{ if $meth.IsWrappingSupported -}}
+			w.Circuit{{ $meth.Name }}, err = manager.CreateCircuit(conf.Prefix + "{{ $.Alias }}.{{ $meth.Name }}", conf.Circuit{{ $meth.Name}}, conf.Defaults)
+			if err != nil {
+				return nil, err
+			}
+
+			w.Retry{{ $meth.Name }} = conf.Retry{{ $meth.Name }}
+			if w.Retry{{ $meth.Name }} == nil {
+				w.Retry{{ $meth.Name }} = conf.Retry
+			}
+
+			w.ShouldSkipError{{ $meth.Name }} = conf.ShouldSkipError{{ $meth.Name }}
+			if w.ShouldSkipError{{ $meth.Name }} == nil {
+				w.ShouldSkipError{{ $meth.Name }} = func({{ $meth.ClassifierErrorParams }}) bool {
+					return w.ShouldSkipError(err)
+				}
+			}
+
+			w.IsBadRequest{{ $meth.Name }} = conf.IsBadRequest{{ $meth.Name }}
+			if w.IsBadRequest{{ $meth.Name }} == nil {
+				w.IsBadRequest{{ $meth.Name }} = func({{ $meth.ClassifierErrorParams }}) bool {
+					return w.IsBadRequest(err)
+				}
+			}
+		{{ end }}
+	{{ end }}
+
+	return w, nil
+}{{ end }}
+
+{{ range .TypeMetadata.Methods }}
+{{ if .IsWrappingSupported -}}
+{{ template "method" $.WithMethod . }}
+{{ end }}
+{{ end }}
+
+{{ block "helpers" . }}{{ if .HedgesMethods -}}
+// hedge calls attempt with the index 0, and again with the index 1 if the first call hasn't finished after the delay.
+// It returns the index and error of the first attempt to succeed, or of the last to fail. The context of an attempt
+// still running is cancelled. A panic of an attempt is raised again in the calling goroutine. Calls aren't hedged if
+// the delay isn't positive
+func (w *{{ .WrapperStructName }}{{ .TypeArguments }}) hedge(ctx context.Context, delay time.Duration, attempt func(context.Context, int) error) (int, error) {
+	if delay <= 0 {
+		return 0, attempt(ctx, 0)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		i     int
+		err   error
+		recovered interface{}
+	}
+	// Buffered so the cancelled attempt doesn't block
+	results := make(chan result, 2)
+	call := func(i int) {
+		// Attempts run in their own goroutines, where a panic would crash the program instead of reaching the caller
+		defer func() {
+			if r := recover(); r != nil {
+				results <- result{i: i, recovered: r}
+			}
+		}()
+		results <- result{i: i, err: attempt(ctx, i)}
+	}
+	go call(0)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	started, finished := 1, 0
+	for {
+		select {
+		case <-timer.C:
+			started++
+			go call(1)
+		case r := <-results:
+			if r.recovered != nil {
+				panic(r.recovered)
+			}
+			finished++
+			if r.err == nil || finished == started {
+				return r.i, r.err
+			}
+		}
+	}
+}
+{{ end }}
+
+{{ if .WrappedMethods -}}
+// {{ .WrapperStructName }}Error is returned by {{ .WrapperStructName }} when a circuit rejects a call because it is
+// open or at its concurrency limit, or when a call times out. Other errors of the embedded methods are returned as is.
+// It implements the circuit.Error interface of cep21/circuit v3
+type {{ .WrapperStructName }}Error struct {
+	// Circuit is the name of the circuit
+	Circuit string
+
+	// Method is the name of the wrapped method
+	Method string
+
+	// Err is the error of the circuit, or of the method if it timed out
+	Err error
+
+	circuitOpen             bool
+	concurrencyLimitReached bool
+	timeout                 bool
+}
+
+func (e *{{ .WrapperStructName }}Error) Error() string {
+	return e.Circuit + ": " + e.Err.Error()
+}
+
+// Unwrap returns the cause
+func (e *{{ .WrapperStructName }}Error) Unwrap() error {
+	return e.Err
+}
+
+// CircuitOpen returns true if the call was rejected because the circuit is open
+func (e *{{ .WrapperStructName }}Error) CircuitOpen() bool {
+	return e.circuitOpen
+}
+
+// ConcurrencyLimitReached returns true if the call was rejected because the circuit reached its concurrency limit
+func (e *{{ .WrapperStructName }}Error) ConcurrencyLimitReached() bool {
+	return e.concurrencyLimitReached
+}
+
+// Timeout returns true if the call exceeded the circuit's timeout
+func (e *{{ .WrapperStructName }}Error) Timeout() bool {
+	return e.timeout
+}
+
+// {{ .KeyedCircuitsTypeName }} lazily creates the keyed circuits of a method of {{ .WrapperStructName }} on the manager.
+// The manager can't remove circuits, so calls with new keys use the circuit of the method once there are maxKeys
+type {{ .KeyedCircuitsTypeName }} struct {
+	manager *circuit.Manager
+	name    string
+	maxKeys int
+	configs []circuit.Config
+
+	mu       sync.Mutex
+	circuits map[string]*circuit.Circuit
+}
+
+func new{{ .WrapperStructName }}KeyedCircuits(manager *circuit.Manager, name string, maxKeys int, configs ...circuit.Config) *{{ .KeyedCircuitsTypeName }} {
+	return &{{ .KeyedCircuitsTypeName }}{
+		manager:  manager,
+		name:     name,
+		maxKeys:  maxKeys,
+		configs:  configs,
+		circuits: make(map[string]*circuit.Circuit),
+	}
+}
+
+// get returns the circuit of the key, creating it if needed. The default circuit is returned for an empty key or once
+// there are maxKeys circuits
+func (k *{{ .KeyedCircuitsTypeName }}) get(key string, defaultCircuit *circuit.Circuit) (*circuit.Circuit, error) {
+	if key == "" {
+		return defaultCircuit, nil
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if c, ok := k.circuits[key]; ok {
+		return c, nil
+	}
+	if len(k.circuits) >= k.maxKeys {
+		return defaultCircuit, nil
+	}
+
+	name := k.name + "." + key
+	c, err := k.manager.CreateCircuit(name, k.configs...)
+	if err != nil {
+		return nil, fmt.Errorf("creating keyed circuit %s: %w", name, err)
+	}
+	k.circuits[key] = c
+	return c, nil
+}
+
+// {{ .WrapperStructName }}PanicError is returned by {{ .WrapperStructName }} when an embedded method panics and
+// RecoverPanics is set
+type {{ .WrapperStructName }}PanicError struct {
+	// Method is the name of the wrapped method
+	Method string
+
+	// Value is the value the method panicked with
+	Value interface{}
+
+	// Stack is the stack trace of the panicking goroutine
+	Stack []byte
+}
+
+func (e *{{ .WrapperStructName }}PanicError) Error() string {
+	return "panic in " + e.Method + ": " + fmt.Sprint(e.Value)
+}
+
+// Unwrap returns the value the method panicked with if it is an error
+func (e *{{ .WrapperStructName }}PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// recoverPanic recovers a panic of the embedded method into the error of the call if RecoverPanics is set. It must be
+// deferred by the call
+func (w *{{ .WrapperStructName }}{{ .TypeArguments }}) recoverPanic(method string, err *error) {
+	if !w.RecoverPanics {
+		return
+	}
+
+	if r := recover(); r != nil {
+		*err = &{{ .WrapperStructName }}PanicError{Method: method, Value: r, Stack: debug.Stack()}
+	}
+}
+
+// repanic panics with the {{ .WrapperStructName }}PanicError of a call if Repanic is set
+func (w *{{ .WrapperStructName }}{{ .TypeArguments }}) repanic(err error) {
+	var perr *{{ .WrapperStructName }}PanicError
+	if w.Repanic && errors.As(err, &perr) {
+		panic(perr)
+	}
+}
+
+// bypassed returns whether a call bypasses the circuits, and reports it to OnBypass
+func (w *{{ .WrapperStructName }}{{ .TypeArguments }}) bypassed(ctx context.Context, method string) bool {
+	if w.Bypass == nil || !w.Bypass(ctx) {
+		return false
+	}
+
+	if w.OnBypass != nil {
+		w.OnBypass(method)
+	}
+	return true
+}
+
+// isCanceledBadRequest returns whether the error of a call is counted as a bad request because the caller's context is
+// done. The circuit's timeout doesn't end the caller's context
+func (w *{{ .WrapperStructName }}{{ .TypeArguments }}) isCanceledBadRequest(callerCtx context.Context, err error) bool {
+	return w.CanceledAsBadRequest && err != nil && callerCtx.Err() != nil
+}
+
+// circuitError wraps the error of a call in a {{ .WrapperStructName }}Error if the circuit rejected the call, or if it
+// failed with context.DeadlineExceeded while the caller's context isn't done
+func (w *{{ .WrapperStructName }}{{ .TypeArguments }}) circuitError(ctx context.Context, c *circuit.Circuit, method string, err error) error {
+	var berr *circuit.SimpleBadRequest
+	if err == nil || errors.As(err, &berr) {
+		return err
+	}
+
+	if cerr, ok := err.(interface {
+		CircuitOpen() bool
+		ConcurrencyLimitReached() bool
+	}); ok {
+		return &{{ .WrapperStructName }}Error{
+			Circuit:                 c.Name(),
+			Method:                  method,
+			Err:                     err,
+			circuitOpen:             cerr.CircuitOpen(),
+			concurrencyLimitReached: cerr.ConcurrencyLimitReached(),
+		}
+	}
+
+	if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+		return &{{ .WrapperStructName }}Error{Circuit: c.Name(), Method: method, Err: err, timeout: true}
+	}
+
+	return err
+}
+
+// run executes the circuit with the policy. Calls are attempted once if the policy is nil
+func (p *{{ .WrapperStructName }}RetryPolicy) run(ctx context.Context, c *circuit.Circuit, runFunc func(context.Context) error, fallbackFunc func(context.Context, error) error) error {
+	if p == nil || p.MaxAttempts < 2 {
+		return c.Execute(ctx, runFunc, fallbackFunc)
+	}
+
+	if p.AroundCircuit {
+		// Falling back on every attempt would stop the retries, so the fallback only gets the error of the last one
+		err := p.retry(ctx, func(ctx context.Context) error {
+			return c.Execute(ctx, runFunc, nil)
+		})
+		if err != nil && fallbackFunc != nil && !circuit.IsBadRequest(err) {
+			return fallbackFunc(ctx, err)
+		}
+		return err
+	}
+
+	return c.Execute(ctx, func(ctx context.Context) error {
+		return p.retry(ctx, runFunc)
+	}, fallbackFunc)
+}
+
+// retry calls f until it succeeds, its error isn't retried, the attempts run out, or the context would be done
+// before the next attempt
+func (p *{{ .WrapperStructName }}RetryPolicy) retry(ctx context.Context, f func(context.Context) error) error {
+	backoff := p.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := f(ctx)
+		if err == nil || attempt >= p.MaxAttempts || !p.isRetried(err) {
+			return err
+		}
+
+		wait := backoff
+		if p.Jitter > 0 {
+			wait -= time.Duration(p.Jitter * rand.Float64() * float64(backoff)) // #nosec G404 jitter doesn't need a secure random
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+	}
+}
+
+// isRetried returns whether the error of an attempt is retried
+func (p *{{ .WrapperStructName }}RetryPolicy) isRetried(err error) bool {
+	var berr *circuit.SimpleBadRequest
+	if errors.As(err, &berr) {
+		return false
+	}
+	if cerr, ok := err.(interface{ CircuitOpen() bool }); ok && cerr.CircuitOpen() {
+		return false
+	}
+	var perr *{{ .WrapperStructName }}PanicError
+	if errors.As(err, &perr) {
+		return false
+	}
+	return p.ShouldRetry == nil || p.ShouldRetry(err)
+}
+{{ end }}{{ end }}
+
+{{ block "interfaceCheck" . }}{{if .IsInterface -}}
+	{{ if .TypeMetadata.TypeParams -}}
+		func _{{ .TypeParamsDeclaration }}() {
+			var _ {{ .EmbeddedType }} = (*{{ .WrapperStructName }}{{ .TypeArguments }})(nil)
+		}
+	{{ else -}}
+		var _ {{ .EmbeddedType }} = (*{{ .WrapperStructName}})(nil)
+	{{ end -}}
+{{ end }}{{ end }}
+`)).Parse(`
+{{ define "method" -}}
+// {{ .Method.Name }} calls the embedded {{ .EmbeddedType }}'s method {{ .Method.Name}} with Circuit{{ .Method.Name }}
+{{- if .Method.Hedged }}. Calls are
+// hedged after Hedge{{ .Method.Name }}
+{{- end }}
+func (w *{{ .WrapperStructName }}{{ .TypeArguments }}) {{ .Method.Name }}({{ .Method.ParamsSignature }}) {{ .Method.ResultsSignature }} {
+	{{ block "methodBody" . -}}
+	{{ if .Method.Hedged -}}
+		{{ template "hedgedMethodBody" . }}
+	{{- else -}}
+		{{ template "circuitMethodBody" . }}
+	{{- end }}
+	{{- end }}
+}
+{{- end }}
+
+{{ define "circuitMethodBody" -}}
+	callerCtx := {{ .Method.ContextExpression }}
+	if w.bypassed(callerCtx, "{{ .Method.Name }}") {
+		return w.{{ .EmbeddedName }}.{{ .Method.Name }}({{ .Method.CallSignatureWithClosure }})
+	}
+
+	c := w.Circuit{{ .Method.Name }}
+	if w.Key{{ .Method.Name }} != nil {
+		var err error
+		if c, err = w.keyedCircuits{{ .Method.Name }}.get(w.Key{{ .Method.Name }}({{ .Method.KeyCallSignature }}), c); err != nil {
+			{{ .Method.ResultsClosureVariableDeclarations -}}
+			return {{ .Method.ResultsClosureVariableReturns }} err
+		}
+	}
+
+	{{ .Method.ResultsClosureVariableDeclarations -}}
+	var skippedErr error
+
+	var fallback func(context.Context, error) error
+	if w.Fallback{{ .Method.Name }} != nil {
+		fallback = func(ctx context.Context, err error) error {
+			{{ if .Method.HasOneMethodResultVariable -}}
+				return w.Fallback{{ .Method.Name }}({{ .Method.FallbackCallSignature }})
+			{{- else -}}
+				{{ .Method.ResultsCircuitVariableAssignments }} = w.Fallback{{ .Method.Name }}({{ .Method.FallbackCallSignature }})
+				return err
+			{{- end }}
+		}
+	}
+
+	err := w.Retry{{ .Method.Name }}.run(callerCtx, c, func(ctx context.Context) (err error) {
+		defer w.recoverPanic("{{ .Method.Name }}", &err)
+
+		{{ .Method.ResultsCircuitVariableAssignments }} = w.{{ .EmbeddedName }}.{{ .Method.Name }}({{ .Method.CallSignatureWithClosure }})
+
+		if w.isCanceledBadRequest(callerCtx, err) {
+			return &circuit.SimpleBadRequest{Err: err}
+		}
+
+		if w.ShouldSkipError{{ .Method.Name }}({{ .Method.FallbackCallSignature }}) {
+			skippedErr = err
+			return nil
+		}
+
+		if w.IsBadRequest{{ .Method.Name }}({{ .Method.FallbackCallSignature }}) {
+			return &circuit.SimpleBadRequest{Err: err}
+		}
+		return err
+	}, fallback)
+	err = w.circuitError(callerCtx, c, "{{ .Method.Name }}", err)
+	w.repanic(err)
+
+	if skippedErr != nil {
+		err = skippedErr
+	}
+
+	// The circuit or its hooks may wrap the bad request
+	var berr *circuit.SimpleBadRequest
+	if errors.As(err, &berr) {
+		err = berr.Err
+	}
+
+	return {{ .Method.ResultsClosureVariableReturns }} err
+{{- end }}
+
+{{ define "hedgedMethodBody" -}}
+	callerCtx := {{ .Method.ContextExpression }}
+	if w.bypassed(callerCtx, "{{ .Method.Name }}") {
+		return w.{{ .EmbeddedName }}.{{ .Method.Name }}({{ .Method.CallSignatureWithClosure }})
+	}
+
+	c := w.Circuit{{ .Method.Name }}
+	if w.Key{{ .Method.Name }} != nil {
+		var err error
+		if c, err = w.keyedCircuits{{ .Method.Name }}.get(w.Key{{ .Method.Name }}({{ .Method.KeyCallSignature }}), c); err != nil {
+			{{ .Method.ResultsClosureVariableDeclarations -}}
+			return {{ .Method.ResultsClosureVariableReturns }} err
+		}
+	}
+
+	{{ .Method.HedgedResultsClosureVariableDeclarations -}}
+	var skippedErr [2]error
+
+	n, err := w.hedge(callerCtx, w.Hedge{{ .Method.Name }}, func(ctx context.Context, i int) error {
+		var fallback func(context.Context, error) error
+		if w.Fallback{{ .Method.Name }} != nil {
+			fallback = func(ctx context.Context, err error) error {
+				{{ if .Method.HasOneMethodResultVariable -}}
+					return w.Fallback{{ .Method.Name }}({{ .Method.FallbackCallSignature }})
+				{{- else -}}
+					{{ .Method.HedgedResultsCircuitVariableAssignments }} = w.Fallback{{ .Method.Name }}({{ .Method.FallbackCallSignature }})
+					return err
+				{{- end }}
+			}
+		}
+
+		return w.Retry{{ .Method.Name }}.run(ctx, c, func(ctx context.Context) (err error) {
+			defer w.recoverPanic("{{ .Method.Name }}", &err)
+
+			{{ .Method.HedgedResultsCircuitVariableAssignments }} = w.{{ .EmbeddedName }}.{{ .Method.Name }}({{ .Method.CallSignatureWithClosure }})
+
+			if w.isCanceledBadRequest(callerCtx, err) {
+				return &circuit.SimpleBadRequest{Err: err}
+			}
+
+			if w.ShouldSkipError{{ .Method.Name }}({{ .Method.FallbackCallSignature }}) {
+				skippedErr[i] = err
+				return nil
+			}
+
+			if w.IsBadRequest{{ .Method.Name }}({{ .Method.FallbackCallSignature }}) {
+				return &circuit.SimpleBadRequest{Err: err}
+			}
+			return err
+		}, fallback)
+	})
+	err = w.circuitError(callerCtx, c, "{{ .Method.Name }}", err)
+	w.repanic(err)
+
+	if skippedErr[n] != nil {
+		err = skippedErr[n]
+	}
+
+	// The circuit or its hooks may wrap the bad request
+	var berr *circuit.SimpleBadRequest
+	if errors.As(err, &berr) {
+		err = berr.Err
+	}
+
+	return {{ .Method.HedgedResultsClosureVariableReturns }} err
+{{- end }}
+`))
+
+// templateLocals are the local variables of the template in the scope of the types of the wrapped methods. Referenced
+// packages with these names are aliased so the variables don't shadow them
+var templateLocals = []string{"manager", "embedded", "conf", "w", "err", "ctx", "callerCtx", "i", "n", "fallback", "skippedErr", "berr", "c"}
+
+// TemplateContext is the data a wrapper template is executed with. Custom templates can use its fields and methods,
+// the methods of the Method type, and the functions lower, upper, quote, and join.
+type TemplateContext struct {
+	// Name of the package the wrapper is generated in
+	PackageName string
+
+	// The name used for the wrapper. ex. "DynamoDB"
+	Alias string
+
+	// Suffix of the cep21/circuit import path. ex. "/v3"
+	VersionSuffix string
+
+	// The parsed type the wrapper is generated for
+	TypeMetadata TypeMetadata
+}
+
+// MethodTemplateContext is the data the "method" template and the method body templates are executed with
+type MethodTemplateContext struct {
+	*TemplateContext
+
+	// The wrapped method
+	Method Method
+}
+
+// WithMethod returns the data of the method templates for the method. ex. {{ template "method" $.WithMethod . }}
+func (t *TemplateContext) WithMethod(m Method) *MethodTemplateContext {
+	return &MethodTemplateContext{TemplateContext: t, Method: m}
+}
+
+// WrappedMethods returns the methods wrapped by circuits
+func (t *TemplateContext) WrappedMethods() []Method {
+	return t.TypeMetadata.WrappedMethods()
+}
+
+// EmbeddedType is the type embedded by the wrapper. Structs are embedded by pointer.
+// ex. "dynamodbiface.DynamoDBAPI"
+func (t *TemplateContext) EmbeddedType() string {
+	if t.IsInterface() {
+		return t.TypeMetadata.TypeInfo.Name
+	}
+	return "*" + t.TypeMetadata.TypeInfo.Name // assume struct with pointer receiver
+}
+
+// EmbeddedName is the name of the embedded field of the wrapper. ex. "DynamoDBAPI"
+func (t *TemplateContext) EmbeddedName() string {
+	return t.TypeMetadata.Name
+}
+
+// WrapperStructName is the name of the wrapper type. ex. "CircuitWrapperDynamoDB"
+func (t *TemplateContext) WrapperStructName() string {
+	return "CircuitWrapper" + t.Alias
+}
+
+// IsInterface returns whether the wrapped type is an interface rather than a struct
+func (t *TemplateContext) IsInterface() bool {
+	return t.TypeMetadata.TypeInfo.IsInterface
+}
+
+// KeyedCircuitsTypeName is the name of the unexported type caching the keyed circuits of a method
+// ex. "circuitWrapperDynamoDBKeyedCircuits"
+func (t *TemplateContext) KeyedCircuitsTypeName() string {
+	return "circuitWrapper" + t.Alias + "KeyedCircuits"
+}
+
+// WrapsWithoutContext returns whether any method without a context param is wrapped with the base context
+func (t *TemplateContext) WrapsWithoutContext() bool {
+	for _, m := range t.TypeMetadata.Methods {
+		if m.WrappedWithoutContext {
+			return true
+		}
+	}
+	return false
+}
+
+// HedgesMethods returns whether calls of any wrapped method can be hedged, which needs the hedge helper
+func (t *TemplateContext) HedgesMethods() bool {
+	for _, m := range t.TypeMetadata.Methods {
+		if m.IsWrappingSupported() && m.Hedged {
+			return true
+		}
+	}
+	return false
+}
+
+// TypeParamsDeclaration declares the type parameters of a generic wrapper. Empty if the type is not generic.
+// ex. "[K comparable, V any]"
+func (t *TemplateContext) TypeParamsDeclaration() string {
+	if len(t.TypeMetadata.TypeParams) == 0 {
+		return ""
+	}
+
+	params := make([]string, 0, len(t.TypeMetadata.TypeParams))
+	for _, tp := range t.TypeMetadata.TypeParams {
+		params = append(params, tp.Name+" "+tp.Constraint.Name)
+	}
+
+	return "[" + strings.Join(params, ", ") + "]"
+}
+
+// TypeArguments instantiates a generic wrapper with its own type parameters. Empty if the type is not generic.
+// ex. "[K, V]"
+func (t *TemplateContext) TypeArguments() string {
+	if len(t.TypeMetadata.TypeParams) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(t.TypeMetadata.TypeParams))
+	for _, tp := range t.TypeMetadata.TypeParams {
+		names = append(names, tp.Name)
+	}
+
+	return "[" + strings.Join(names, ", ") + "]"
+}
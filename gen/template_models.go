@@ -0,0 +1,415 @@
+// Copyright 2019 Twitch Interactive, Inc.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the License is
+// located at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// or in the "license" file accompanying this file. This file is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package gen
+
+import (
+	"fmt"
+	"time"
+)
+
+// This file contains structs used in the wrapper generating templates.
+
+// TypeMetadata stores metadata about a Go type.
+type TypeMetadata struct {
+	// Name of the type without the package qualifier or type arguments. ex. "Store" for "Store[K, V]"
+	Name string
+
+	// Name of the package this type is defined in.
+	PackageName string
+
+	// Path to the package this type is defined in.
+	PackagePath string
+
+	// Holds type information about this type
+	TypeInfo TypeInfo
+
+	// Type parameters of this type if it is generic and not instantiated
+	TypeParams []TypeParam
+
+	// Imports of this type from all the methods
+	Imports []Import
+
+	// Methods of this type
+	Methods []Method
+}
+
+// WrappedMethods returns the methods of the type that are wrapped by a circuit
+func (t TypeMetadata) WrappedMethods() []Method {
+	var methods []Method
+	for _, m := range t.Methods {
+		if m.IsWrappingSupported() {
+			methods = append(methods, m)
+		}
+	}
+	return methods
+}
+
+// Method represents a method function on a type
+type Method struct {
+	// The name of the method on the type
+	Name string
+
+	// Input params
+	Params []TypeInfo
+
+	// Return results
+	Results []TypeInfo
+
+	// Whether this method is variadic
+	Variadic bool
+
+	// Index of the param the context is taken from. -1 if there is none
+	ContextParamIndex int
+
+	// Accessor expression resolving the context from the context param. ex. ".Context()". Empty if the param is
+	// the context itself
+	ContextAccessor string
+
+	// Whether the method has no context param and is wrapped with the wrapper's base context
+	WrappedWithoutContext bool
+
+	// Why the method is not wrapped by a circuit and is only passed through to the embedded type. Empty if wrapped.
+	// ex. "does not accept a context.Context param"
+	SkipReason string
+
+	// Whether calls of the method can be hedged with a second call
+	Hedged bool
+
+	// The default delay before hedging a call. Calls aren't hedged by default if zero
+	HedgeDelay time.Duration
+
+	// The default timeout of the method's circuit. The circuit's config decides if zero
+	Timeout time.Duration
+}
+
+// TypeInfo stores the name and whether it is an interface
+type TypeInfo struct {
+	// The name of the type with or without the package qualifier. The qualifier is set appropriately, using the
+	// import alias if the package is aliased
+	// Examples:
+	//
+	//		"aws.Context" or "Context"
+	//		"error would be "error"
+	Name string
+
+	// The name of the type without the package qualifier. Ex. "aws.Context" would be "Context"
+	NameWithoutQualifier string
+
+	// Whether this type is an interface
+	IsInterface bool
+}
+
+// TypeParam represents a type parameter of a generic type
+type TypeParam struct {
+	// The name of the type parameter. ex. "K"
+	Name string
+
+	// The constraint of the type parameter. ex. "comparable"
+	Constraint TypeInfo
+}
+
+// Import represents a package import
+type Import struct {
+	// ex. "github.com/aws/aws-sdk-go/service/dynamodb"
+	Path string
+
+	// Set if the package name collides with another import. Types from this package are qualified with the alias.
+	// ex. "s3types" for "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	Alias string
+}
+
+// ParamsSignature generates the signature for the methods params. The context param is named "ctx"
+// ex. "ctx aws.Context, p1 *dynamodb.BatchGetItemInput"
+func (m Method) ParamsSignature(overrides ...string) string {
+	s := ""
+	mt := m.Params
+	l := len(mt)
+
+	for i := 0; i < l; i++ {
+		varName := m.paramName(i)
+
+		if i < len(overrides) {
+			varName = overrides[i]
+		}
+
+		if i == l-1 && m.Variadic {
+			const sliceChars = "[]"
+			// the qualfied name contains the "[]" at the beginning, so chop it off
+			s += fmt.Sprintf("%s ...%s", varName, mt[i].Name[len(sliceChars):])
+		} else {
+			s += fmt.Sprintf("%s %s", varName, mt[i].Name)
+			if i < l-1 {
+				s += ", "
+			}
+		}
+	}
+
+	return s
+}
+
+// ParamNames returns the variable names of the method's params in the wrapper, as named by ParamsSignature
+// ex. ["ctx", "p1", "p2"]
+func (m Method) ParamNames() []string {
+	names := make([]string, len(m.Params))
+	for i := range m.Params {
+		names[i] = m.paramName(i)
+	}
+	return names
+}
+
+// CallSignatureWithClosure generates the signature for calling the embedded interface with a closure
+func (m Method) CallSignatureWithClosure() string {
+	s := ""
+	mt := m.Params
+	l := len(mt)
+
+	for i := 0; i < l; i++ {
+		if i == l-1 && m.Variadic {
+			s += fmt.Sprintf("p%d...", i)
+		} else {
+			s += m.paramName(i)
+
+			if i < l-1 {
+				s += ", "
+			}
+		}
+	}
+
+	return s
+}
+
+// FallbackSignature generates the type of the fallback function of the method. It takes the method's params, with a
+// variadic param as a slice, followed by the error of the circuit, and returns the method's results.
+// ex. "func(aws.Context, *dynamodb.GetItemInput, []request.Option, error) (*dynamodb.GetItemOutput, error)"
+func (m Method) FallbackSignature() string {
+	s := "func("
+	for _, p := range m.Params {
+		s += p.Name + ", "
+	}
+	return s + "error) " + m.ResultsSignature()
+}
+
+// ClassifierSignature generates the type of the error classifiers of the method, which take the method's params, with a
+// variadic param as a slice, followed by the error of the call.
+// ex. "func(aws.Context, *dynamodb.GetItemInput, []request.Option, error) bool"
+func (m Method) ClassifierSignature() string {
+	s := "func("
+	for _, p := range m.Params {
+		s += p.Name + ", "
+	}
+	return s + "error) bool"
+}
+
+// ClassifierErrorParams generates the params of an error classifier of the method that only uses the error "err"
+// ex. "_ aws.Context, _ *dynamodb.GetItemInput, _ []request.Option, err error"
+func (m Method) ClassifierErrorParams() string {
+	s := ""
+	for _, p := range m.Params {
+		s += "_ " + p.Name + ", "
+	}
+	return s + "err error"
+}
+
+// KeySignature generates the type of the key function of the method, which takes the method's params, with a
+// variadic param as a slice.
+// ex. "func(aws.Context, *dynamodb.GetItemInput, []request.Option) string"
+func (m Method) KeySignature() string {
+	s := "func("
+	for i, p := range m.Params {
+		if i > 0 {
+			s += ", "
+		}
+		s += p.Name
+	}
+	return s + ") string"
+}
+
+// KeyCallSignature generates the arguments for calling the key function of the method
+// ex. "ctx, p1, p2"
+func (m Method) KeyCallSignature() string {
+	s := ""
+	for i := range m.Params {
+		if i > 0 {
+			s += ", "
+		}
+		s += m.paramName(i)
+	}
+	return s
+}
+
+// FallbackCallSignature generates the arguments for calling the fallback function of the method within a closure
+// ex. "ctx, p1, p2, err"
+func (m Method) FallbackCallSignature() string {
+	s := ""
+	for i := range m.Params {
+		s += m.paramName(i) + ", "
+	}
+	return s + "err"
+}
+
+// ContextExpression generates the expression for the context passed to the circuit. Methods without a context param
+// use the base context of the wrapper "w".
+// ex. "ctx", "p1.Context()", or "w.BaseContext()"
+func (m Method) ContextExpression() string {
+	if m.WrappedWithoutContext {
+		return "w.BaseContext()"
+	}
+	return m.paramName(m.ContextParamIndex) + m.ContextAccessor
+}
+
+// paramName returns the variable name of the param at index i. The context param is named "ctx" unless the context
+// is resolved from it with an accessor.
+func (m Method) paramName(i int) string {
+	if i == m.ContextParamIndex && m.ContextAccessor == "" {
+		return "ctx"
+	}
+	return fmt.Sprintf("p%d", i)
+}
+
+// ResultsSignature generates the signature for the methods results
+// ex. "(*dynamodb.BatchGetItemOutput, error)"
+func (m Method) ResultsSignature() string {
+	mt := m.Results
+	if len(mt) == 1 {
+		return mt[0].Name
+	}
+
+	s := "("
+	l := len(mt)
+	for i := 0; i < l; i++ {
+		if i == l-1 {
+			s += mt[i].Name
+		} else {
+			s += mt[i].Name + ", "
+		}
+	}
+
+	return s + ")"
+}
+
+// ResultsClosureVariableDeclarations generates the variable declarations needed for making a call with a closure. These variables are needed
+// outside the function scope (ex. (*circuit.Circuit).Run()).
+// ex. "var r0 *dynamodb.UpdateItemInput
+func (m Method) ResultsClosureVariableDeclarations() string {
+	s := ""
+	for i, t := range m.Results[:len(m.Results)-1] {
+		s += fmt.Sprintf("var r%d %s\n", i, t.Name)
+	}
+
+	return s
+}
+
+// HasOneMethodResultVariable returns whether there is exactly one return value
+func (m Method) HasOneMethodResultVariable() bool {
+	return len(m.Results) == 1
+}
+
+// ResultsCircuitVariableAssignments generates the variable names needed when assigning the embedded interface method call.
+// ex. "r0, err"
+func (m Method) ResultsCircuitVariableAssignments() string {
+	s := ""
+
+	for i := range m.Results[:len(m.Results)-1] {
+		s += fmt.Sprintf("r%d, ", i)
+	}
+
+	s += "err"
+
+	return s
+}
+
+// ResultsClosureVariableReturns generates the variable names needed when returning the results of a closure wrapped method call.
+// ex. "r0, err"
+func (m Method) ResultsClosureVariableReturns() string {
+	s := ""
+	for i := range m.Results[:len(m.Results)-1] {
+		s += fmt.Sprintf("r%d, ", i)
+	}
+
+	return s
+}
+
+// HedgedResultsClosureVariableDeclarations generates the variable declarations of a hedged call. Every attempt has its
+// own results, indexed by the attempt.
+// ex. "var r0 [2]*dynamodb.UpdateItemInput
+func (m Method) HedgedResultsClosureVariableDeclarations() string {
+	s := ""
+	for i, t := range m.Results[:len(m.Results)-1] {
+		s += fmt.Sprintf("var r%d [2]%s\n", i, t.Name)
+	}
+
+	return s
+}
+
+// HedgedResultsCircuitVariableAssignments generates the variable names of the attempt "i" of a hedged call when
+// assigning the embedded interface method call.
+// ex. "r0[i], err"
+func (m Method) HedgedResultsCircuitVariableAssignments() string {
+	s := ""
+	for i := range m.Results[:len(m.Results)-1] {
+		s += fmt.Sprintf("r%d[i], ", i)
+	}
+
+	return s + "err"
+}
+
+// HedgedResultsClosureVariableReturns generates the variable names of the attempt "n" of a hedged call when returning
+// its results.
+// ex. "r0[n], "
+func (m Method) HedgedResultsClosureVariableReturns() string {
+	s := ""
+	for i := range m.Results[:len(m.Results)-1] {
+		s += fmt.Sprintf("r%d[n], ", i)
+	}
+
+	return s
+}
+
+// HedgeDelayExpression generates the expression of the default hedge delay
+// ex. "50 * time.Millisecond"
+func (m Method) HedgeDelayExpression() string {
+	return durationExpression(m.HedgeDelay)
+}
+
+// TimeoutExpression generates the expression of the default timeout
+// ex. "200 * time.Millisecond"
+func (m Method) TimeoutExpression() string {
+	return durationExpression(m.Timeout)
+}
+
+// durationExpression generates the expression of the duration in its largest whole unit
+func durationExpression(d time.Duration) string {
+	units := []struct {
+		d    time.Duration
+		name string
+	}{
+		{time.Hour, "time.Hour"},
+		{time.Minute, "time.Minute"},
+		{time.Second, "time.Second"},
+		{time.Millisecond, "time.Millisecond"},
+		{time.Microsecond, "time.Microsecond"},
+	}
+	for _, u := range units {
+		if d != 0 && d%u.d == 0 {
+			return fmt.Sprintf("%d * %s", d/u.d, u.name)
+		}
+	}
+
+	return fmt.Sprintf("time.Duration(%d)", int64(d))
+}
+
+// IsWrappingSupported returns true only if the method supports context and returns an error.
+func (m Method) IsWrappingSupported() bool {
+	return m.SkipReason == ""
+}
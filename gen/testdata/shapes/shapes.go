@@ -0,0 +1,157 @@
+// Copyright 2019 Twitch Interactive, Inc.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the License is
+// located at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// or in the "license" file accompanying this file. This file is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package shapes contains types covering every shape of go/types reachable from a method signature. Each type has a
+// golden file of its generated circuit wrapper in testdata/golden.
+package shapes
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	breakercircuit "github.com/twitchtv/circuitgen/gen/testdata/shapes/breaker/circuit"
+	dynamodbtypes "github.com/twitchtv/circuitgen/gen/testdata/shapes/dynamodb/types"
+	legacycontext "github.com/twitchtv/circuitgen/gen/testdata/shapes/legacy/context"
+	s3types "github.com/twitchtv/circuitgen/gen/testdata/shapes/s3/types"
+	"github.com/twitchtv/circuitgen/internal/circuitgentest/model"
+	"github.com/twitchtv/circuitgen/internal/circuitgentest/rep"
+)
+
+// ChanShape has channel params and results
+type ChanShape interface {
+	Subscribe(ctx context.Context, topic string) (<-chan *model.Result, error)
+	Send(ctx context.Context, ch chan<- rep.PublishInput) error
+}
+
+// ArrayShape has array params and results
+type ArrayShape interface {
+	Batch(ctx context.Context, inputs [4]rep.PublishInput) ([2]*model.Result, error)
+}
+
+// FuncShape has function params with their own params and results
+type FuncShape interface {
+	Each(ctx context.Context, fn func(*model.Result) (time.Duration, error)) error
+}
+
+// StructShape has unnamed struct params and results
+type StructShape interface {
+	Lookup(ctx context.Context, key struct {
+		Input   rep.PublishInput
+		Timeout time.Duration
+	}) (struct{ Result *model.Result }, error)
+}
+
+// InterfaceShape has unnamed interface params with method sets and embedded interfaces
+type InterfaceShape interface {
+	Read(ctx context.Context, r interface {
+		io.Reader
+		Result() *model.Result
+	}) error
+}
+
+// PassThroughShape has no methods wrapped by a circuit, so its wrapper has no retries
+type PassThroughShape interface {
+	Close() error
+	Len() int
+}
+
+// TypeParamShape has type parameters with constraints from other packages
+type TypeParamShape[K fmt.Stringer, V interface{ ~int | time.Duration }] interface {
+	Get(ctx context.Context, key K) (map[string]V, error)
+}
+
+// CollisionShape references packages whose names collide with each other and with the imports of the wrapper
+type CollisionShape interface {
+	Copy(ctx context.Context, obj *s3types.Object, values legacycontext.Values) (dynamodbtypes.Item, error)
+	Configure(ctx context.Context, conf breakercircuit.Config) error
+}
+
+// AliasContext is an alias of context.Context, like aws.Context
+type AliasContext = context.Context
+
+// EmbeddedContext is an interface with the same method set as context.Context
+type EmbeddedContext interface {
+	context.Context
+}
+
+// HandlerContext is an interface extending context.Context, like echo.Context
+type HandlerContext interface {
+	context.Context
+	Param(name string) string
+}
+
+// RequestContext is a struct implementing context.Context, like *gin.Context
+type RequestContext struct {
+	context.Context
+}
+
+// ContextShape has methods with params that are or only look like a context. Only methods accepting a param
+// interchangeable with context.Context are wrapped
+type ContextShape interface {
+	Std(ctx context.Context) error
+	Alias(ctx AliasContext) error
+	Embedded(ctx EmbeddedContext) error
+	Handler(ctx HandlerContext) error
+	Request(ctx *RequestContext) error
+	NoError(ctx context.Context) bool
+}
+
+// HedgeShape has methods hedged with directives, with and without a default delay
+type HedgeShape interface {
+	//circuitgen:hedge=1m30s
+	Lookup(ctx context.Context, keys ...string) (map[string]*model.Result, int, error)
+	Ping(ctx context.Context) error //circuitgen:hedge
+	Write(ctx context.Context, input rep.PublishInput) error
+}
+
+// DirectiveShape is wrapped by the scan command, with methods skipped and given a timeout by directives
+//
+//circuitgen:wrap alias=Directive out=./wrappers exclude=Close
+type DirectiveShape interface {
+	//circuitgen:timeout=200ms
+	Get(ctx context.Context, key string) (*model.Result, error)
+	// Put passes through to the embedded type
+	//
+	//circuitgen:skip
+	Put(ctx context.Context, input rep.PublishInput) error
+	Close(ctx context.Context) error
+}
+
+// OrderReader is embedded by OrderShape
+type OrderReader interface {
+	Read(ctx context.Context, key string) (string, error)
+	Exists(ctx context.Context, key string) (bool, error)
+}
+
+// OrderShape declares its methods out of alphabetical order, with embedded interfaces in between
+type OrderShape interface {
+	Write(ctx context.Context, key, value string) error
+	OrderReader
+	io.Closer
+	Delete(ctx context.Context, key string) error
+}
+
+// OrderStruct declares its methods out of alphabetical order
+type OrderStruct struct{}
+
+// Write does nothing
+func (OrderStruct) Write(ctx context.Context, key, value string) error {
+	return nil
+}
+
+// Delete does nothing
+func (*OrderStruct) Delete(ctx context.Context, key string) error {
+	return nil
+}
@@ -1,18 +1,23 @@
 module github.com/twitchtv/circuitgen
 
-go 1.11
+go 1.25.0
 
 require (
 	github.com/cep21/circuit v2.4.1+incompatible
 	github.com/cep21/circuit/v3 v3.1.0
-	github.com/inconshreveable/mousetrap v1.0.0 // indirect
-	github.com/kisielk/errcheck v1.2.0
-	github.com/securego/gosec v0.0.0-20190510081509-ee80733faf72
+	github.com/pmezard/go-difflib v1.0.0
 	github.com/spf13/cobra v0.0.3
-	github.com/spf13/pflag v1.0.3
-	github.com/stretchr/objx v0.1.1 // indirect
 	github.com/stretchr/testify v1.3.0
-	golang.org/x/lint v0.0.0-20190409202823-959b441ac422
-	golang.org/x/tools v0.1.10
-	honnef.co/go/tools v0.0.1-2019.2.3
+	golang.org/x/tools v0.47.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/inconshreveable/mousetrap v1.0.0 // indirect
+	github.com/spf13/pflag v1.0.3 // indirect
+	github.com/stretchr/objx v0.1.1 // indirect
+	golang.org/x/mod v0.37.0 // indirect
+	golang.org/x/sync v0.21.0 // indirect
+	gopkg.in/check.v1 v1.0.0-20180628173108-788fd7840127 // indirect
 )
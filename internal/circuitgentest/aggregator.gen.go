@@ -4,6 +4,12 @@ package circuitgentest
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"runtime/debug"
+	"sync"
+	"time"
 
 	"github.com/cep21/circuit"
 )
@@ -15,16 +21,102 @@ type CircuitWrapperAggregatorConfig struct {
 	ShouldSkipError func(error) bool
 
 	// IsBadRequest is an optional bad request checker. It is useful to not count user errors as faults
+	//
+	// Both checkers receive the error of the embedded method as is, so wrapped errors can be checked with errors.Is
+	// and errors.As
 	IsBadRequest func(error) bool
 
+	// BaseContext returns the context for circuits of methods without a context param. Defaults to context.Background
+	BaseContext func() context.Context
+
 	// Prefix is prepended to all circuit names
 	Prefix string
 
 	// Defaults are used for all created circuits. Per-circuit configs override this
 	Defaults circuit.Config
 
-	// CircuitIncSum is the configuration used for the IncSum circuit. This overrides values set by Defaults
+	// MaxKeys bounds the number of keyed circuits of each method. Calls with new keys use the circuit of the method once
+	// it is reached. Defaults to 1000
+	MaxKeys int
+
+	// Retry is the retry policy of all methods. Calls are attempted once if nil
+	Retry *CircuitWrapperAggregatorRetryPolicy
+
+	// RecoverPanics recovers panics of the embedded methods into a CircuitWrapperAggregatorPanicError, which the
+	// circuit counts as a failure. Panics are not recovered by default
+	RecoverPanics bool
+
+	// Repanic panics again with the CircuitWrapperAggregatorPanicError of a recovered panic after the circuit counted
+	// it, unless a fallback handled it. This has no effect without RecoverPanics
+	Repanic bool
+
+	// CanceledAsBadRequest counts calls failing after the caller's context is done as bad requests, which don't fall
+	// back and aren't retried. Otherwise the circuit counts them as interrupts, unless it ignores interrupts. Either
+	// way they don't count as failures, unlike the circuit's timeouts
+	CanceledAsBadRequest bool
+
+	// Bypass returns whether calls with the context bypass the circuits and call the embedded methods directly, ex.
+	// for admin tools. Bypassed calls aren't tracked by the circuits. Set it to circuitbypass.IsBypassed to bypass
+	// calls with contexts from circuitbypass.WithCircuitBypass. Calls aren't bypassed if nil
+	Bypass func(context.Context) bool
+
+	// OnBypass is called with the method name of every bypassed call if set, ex. to report metrics
+	OnBypass func(method string)
+
+	// CircuitIncSum is the configuration used for the IncSum circuit. This overrides values set by Defaults. Its timeout defaults to 200ms
 	CircuitIncSum circuit.Config
+	// FallbackIncSum is called with the params of IncSum and the circuit error when the call fails or
+	// the circuit is open. Its results are returned instead. Bad requests and skipped errors don't fall back
+	FallbackIncSum func(context.Context, int, error) error
+	// RetryIncSum is the retry policy of IncSum. This overrides Retry
+	RetryIncSum *CircuitWrapperAggregatorRetryPolicy
+	// KeyIncSum returns the key of a call of IncSum. Calls with a non-empty key use a circuit of the key
+	// named Prefix+"Aggregator.IncSum.<key>", created on first use with CircuitIncSum. Otherwise calls use
+	// CircuitIncSum
+	KeyIncSum func(context.Context, int) string
+	// ShouldSkipErrorIncSum overrides ShouldSkipError for IncSum. It receives the params of the call
+	ShouldSkipErrorIncSum func(context.Context, int, error) bool
+	// IsBadRequestIncSum overrides IsBadRequest for IncSum. It receives the params of the call
+	IsBadRequestIncSum func(context.Context, int, error) bool
+	// CircuitReset is the configuration used for the Reset circuit. This overrides values set by Defaults
+	CircuitReset circuit.Config
+	// FallbackReset is called with the params of Reset and the circuit error when the call fails or
+	// the circuit is open. Its results are returned instead. Bad requests and skipped errors don't fall back
+	FallbackReset func(error) error
+	// RetryReset is the retry policy of Reset. This overrides Retry
+	RetryReset *CircuitWrapperAggregatorRetryPolicy
+	// KeyReset returns the key of a call of Reset. Calls with a non-empty key use a circuit of the key
+	// named Prefix+"Aggregator.Reset.<key>", created on first use with CircuitReset. Otherwise calls use
+	// CircuitReset
+	KeyReset func() string
+	// ShouldSkipErrorReset overrides ShouldSkipError for Reset. It receives the params of the call
+	ShouldSkipErrorReset func(error) bool
+	// IsBadRequestReset overrides IsBadRequest for Reset. It receives the params of the call
+	IsBadRequestReset func(error) bool
+}
+
+// CircuitWrapperAggregatorRetryPolicy configures retrying failed calls of CircuitWrapperAggregator. Bad requests,
+// skipped errors, open circuits, and recovered panics are never retried
+type CircuitWrapperAggregatorRetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts of a call, including the first. Calls are attempted once if less than 2
+	MaxAttempts int
+
+	// InitialBackoff is the backoff before the first retry. It doubles for every retry after
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the backoff between retries if set
+	MaxBackoff time.Duration
+
+	// Jitter is the fraction of each backoff that is randomly subtracted from it, between 0 and 1. 1 is full jitter
+	Jitter float64
+
+	// ShouldRetry determines whether an error is retried. Defaults to retrying all errors
+	ShouldRetry func(error) bool
+
+	// AroundCircuit retries calls around the circuit, so every attempt is tracked by the circuit and has its own
+	// timeout. The fallback is called once the attempts run out. Otherwise calls are retried inside the circuit, which
+	// tracks all attempts as one call bounded by its timeout
+	AroundCircuit bool
 }
 
 // CircuitWrapperAggregator is a circuit wrapper for *Aggregator
@@ -38,8 +130,50 @@ type CircuitWrapperAggregator struct {
 	// IsBadRequest checks whether to count a user error against the circuit. It is recommended to set this
 	IsBadRequest func(error) bool
 
+	// RecoverPanics recovers panics of the embedded methods into a CircuitWrapperAggregatorPanicError
+	RecoverPanics bool
+
+	// Repanic panics again with recovered panics after the circuit counted them
+	Repanic bool
+
+	// CanceledAsBadRequest counts calls failing after the caller's context is done as bad requests
+	CanceledAsBadRequest bool
+
+	// Bypass returns whether calls with the context bypass the circuits
+	Bypass func(context.Context) bool
+
+	// OnBypass is called with the method name of every bypassed call
+	OnBypass func(method string)
+
+	// BaseContext returns the context for circuits of methods without a context param
+	BaseContext func() context.Context
+
 	// CircuitIncSum is the circuit for method IncSum
 	CircuitIncSum *circuit.Circuit
+	// FallbackIncSum is the optional fallback for method IncSum
+	FallbackIncSum func(context.Context, int, error) error
+	// RetryIncSum is the optional retry policy for method IncSum
+	RetryIncSum *CircuitWrapperAggregatorRetryPolicy
+	// KeyIncSum is the optional key function of the keyed circuits for method IncSum
+	KeyIncSum           func(context.Context, int) string
+	keyedCircuitsIncSum *circuitWrapperAggregatorKeyedCircuits
+	// ShouldSkipErrorIncSum determines whether an error of method IncSum should be skipped
+	ShouldSkipErrorIncSum func(context.Context, int, error) bool
+	// IsBadRequestIncSum checks whether to count an error of method IncSum against the circuit
+	IsBadRequestIncSum func(context.Context, int, error) bool
+	// CircuitReset is the circuit for method Reset
+	CircuitReset *circuit.Circuit
+	// FallbackReset is the optional fallback for method Reset
+	FallbackReset func(error) error
+	// RetryReset is the optional retry policy for method Reset
+	RetryReset *CircuitWrapperAggregatorRetryPolicy
+	// KeyReset is the optional key function of the keyed circuits for method Reset
+	KeyReset           func() string
+	keyedCircuitsReset *circuitWrapperAggregatorKeyedCircuits
+	// ShouldSkipErrorReset determines whether an error of method Reset should be skipped
+	ShouldSkipErrorReset func(error) bool
+	// IsBadRequestReset checks whether to count an error of method Reset against the circuit
+	IsBadRequestReset func(error) bool
 }
 
 // NewCircuitWrapperAggregator creates a new circuit wrapper and initializes circuits
@@ -60,10 +194,33 @@ func NewCircuitWrapperAggregator(
 		}
 	}
 
+	if conf.BaseContext == nil {
+		conf.BaseContext = context.Background
+	}
+	if conf.MaxKeys <= 0 {
+		conf.MaxKeys = 1000
+	}
+
+	if conf.CircuitIncSum.Execution.Timeout == 0 {
+		conf.CircuitIncSum.Execution.Timeout = 200 * time.Millisecond
+	}
+
 	w := &CircuitWrapperAggregator{
-		Aggregator:      embedded,
-		ShouldSkipError: conf.ShouldSkipError,
-		IsBadRequest:    conf.IsBadRequest,
+		Aggregator:           embedded,
+		ShouldSkipError:      conf.ShouldSkipError,
+		IsBadRequest:         conf.IsBadRequest,
+		RecoverPanics:        conf.RecoverPanics,
+		Repanic:              conf.Repanic,
+		CanceledAsBadRequest: conf.CanceledAsBadRequest,
+		Bypass:               conf.Bypass,
+		OnBypass:             conf.OnBypass,
+		BaseContext:          conf.BaseContext,
+		FallbackIncSum:       conf.FallbackIncSum,
+		KeyIncSum:            conf.KeyIncSum,
+		keyedCircuitsIncSum:  newCircuitWrapperAggregatorKeyedCircuits(manager, conf.Prefix+"Aggregator.IncSum", conf.MaxKeys, conf.CircuitIncSum, conf.Defaults),
+		FallbackReset:        conf.FallbackReset,
+		KeyReset:             conf.KeyReset,
+		keyedCircuitsReset:   newCircuitWrapperAggregatorKeyedCircuits(manager, conf.Prefix+"Aggregator.Reset", conf.MaxKeys, conf.CircuitReset, conf.Defaults),
 	}
 
 	var err error
@@ -72,34 +229,418 @@ func NewCircuitWrapperAggregator(
 		return nil, err
 	}
 
+	w.RetryIncSum = conf.RetryIncSum
+	if w.RetryIncSum == nil {
+		w.RetryIncSum = conf.Retry
+	}
+
+	w.ShouldSkipErrorIncSum = conf.ShouldSkipErrorIncSum
+	if w.ShouldSkipErrorIncSum == nil {
+		w.ShouldSkipErrorIncSum = func(_ context.Context, _ int, err error) bool {
+			return w.ShouldSkipError(err)
+		}
+	}
+
+	w.IsBadRequestIncSum = conf.IsBadRequestIncSum
+	if w.IsBadRequestIncSum == nil {
+		w.IsBadRequestIncSum = func(_ context.Context, _ int, err error) bool {
+			return w.IsBadRequest(err)
+		}
+	}
+
+	w.CircuitReset, err = manager.CreateCircuit(conf.Prefix+"Aggregator.Reset", conf.CircuitReset, conf.Defaults)
+	if err != nil {
+		return nil, err
+	}
+
+	w.RetryReset = conf.RetryReset
+	if w.RetryReset == nil {
+		w.RetryReset = conf.Retry
+	}
+
+	w.ShouldSkipErrorReset = conf.ShouldSkipErrorReset
+	if w.ShouldSkipErrorReset == nil {
+		w.ShouldSkipErrorReset = func(err error) bool {
+			return w.ShouldSkipError(err)
+		}
+	}
+
+	w.IsBadRequestReset = conf.IsBadRequestReset
+	if w.IsBadRequestReset == nil {
+		w.IsBadRequestReset = func(err error) bool {
+			return w.IsBadRequest(err)
+		}
+	}
+
 	return w, nil
 }
 
 // IncSum calls the embedded *Aggregator's method IncSum with CircuitIncSum
 func (w *CircuitWrapperAggregator) IncSum(ctx context.Context, p1 int) error {
+	callerCtx := ctx
+	if w.bypassed(callerCtx, "IncSum") {
+		return w.Aggregator.IncSum(ctx, p1)
+	}
+
+	c := w.CircuitIncSum
+	if w.KeyIncSum != nil {
+		var err error
+		if c, err = w.keyedCircuitsIncSum.get(w.KeyIncSum(ctx, p1), c); err != nil {
+			return err
+		}
+	}
+
 	var skippedErr error
 
-	err := w.CircuitIncSum.Run(ctx, func(ctx context.Context) error {
-		err := w.Aggregator.IncSum(ctx, p1)
+	var fallback func(context.Context, error) error
+	if w.FallbackIncSum != nil {
+		fallback = func(ctx context.Context, err error) error {
+			return w.FallbackIncSum(ctx, p1, err)
+		}
+	}
+
+	err := w.RetryIncSum.run(callerCtx, c, func(ctx context.Context) (err error) {
+		defer w.recoverPanic("IncSum", &err)
+
+		err = w.Aggregator.IncSum(ctx, p1)
+
+		if w.isCanceledBadRequest(callerCtx, err) {
+			return &circuit.SimpleBadRequest{Err: err}
+		}
+
+		if w.ShouldSkipErrorIncSum(ctx, p1, err) {
+			skippedErr = err
+			return nil
+		}
+
+		if w.IsBadRequestIncSum(ctx, p1, err) {
+			return &circuit.SimpleBadRequest{Err: err}
+		}
+		return err
+	}, fallback)
+	err = w.circuitError(callerCtx, c, "IncSum", err)
+	w.repanic(err)
+
+	if skippedErr != nil {
+		err = skippedErr
+	}
+
+	// The circuit or its hooks may wrap the bad request
+	var berr *circuit.SimpleBadRequest
+	if errors.As(err, &berr) {
+		err = berr.Err
+	}
+
+	return err
+}
+
+// Reset calls the embedded *Aggregator's method Reset with CircuitReset
+func (w *CircuitWrapperAggregator) Reset() error {
+	callerCtx := w.BaseContext()
+	if w.bypassed(callerCtx, "Reset") {
+		return w.Aggregator.Reset()
+	}
+
+	c := w.CircuitReset
+	if w.KeyReset != nil {
+		var err error
+		if c, err = w.keyedCircuitsReset.get(w.KeyReset(), c); err != nil {
+			return err
+		}
+	}
+
+	var skippedErr error
+
+	var fallback func(context.Context, error) error
+	if w.FallbackReset != nil {
+		fallback = func(ctx context.Context, err error) error {
+			return w.FallbackReset(err)
+		}
+	}
+
+	err := w.RetryReset.run(callerCtx, c, func(ctx context.Context) (err error) {
+		defer w.recoverPanic("Reset", &err)
+
+		err = w.Aggregator.Reset()
+
+		if w.isCanceledBadRequest(callerCtx, err) {
+			return &circuit.SimpleBadRequest{Err: err}
+		}
 
-		if w.ShouldSkipError(err) {
+		if w.ShouldSkipErrorReset(err) {
 			skippedErr = err
 			return nil
 		}
 
-		if w.IsBadRequest(err) {
+		if w.IsBadRequestReset(err) {
 			return &circuit.SimpleBadRequest{Err: err}
 		}
 		return err
-	})
+	}, fallback)
+	err = w.circuitError(callerCtx, c, "Reset", err)
+	w.repanic(err)
 
 	if skippedErr != nil {
 		err = skippedErr
 	}
 
-	if berr, ok := err.(*circuit.SimpleBadRequest); ok {
+	// The circuit or its hooks may wrap the bad request
+	var berr *circuit.SimpleBadRequest
+	if errors.As(err, &berr) {
 		err = berr.Err
 	}
 
 	return err
 }
+
+// CircuitWrapperAggregatorError is returned by CircuitWrapperAggregator when a circuit rejects a call because it is
+// open or at its concurrency limit, or when a call times out. Other errors of the embedded methods are returned as is.
+// It implements the circuit.Error interface of cep21/circuit v3
+type CircuitWrapperAggregatorError struct {
+	// Circuit is the name of the circuit
+	Circuit string
+
+	// Method is the name of the wrapped method
+	Method string
+
+	// Err is the error of the circuit, or of the method if it timed out
+	Err error
+
+	circuitOpen             bool
+	concurrencyLimitReached bool
+	timeout                 bool
+}
+
+func (e *CircuitWrapperAggregatorError) Error() string {
+	return e.Circuit + ": " + e.Err.Error()
+}
+
+// Unwrap returns the cause
+func (e *CircuitWrapperAggregatorError) Unwrap() error {
+	return e.Err
+}
+
+// CircuitOpen returns true if the call was rejected because the circuit is open
+func (e *CircuitWrapperAggregatorError) CircuitOpen() bool {
+	return e.circuitOpen
+}
+
+// ConcurrencyLimitReached returns true if the call was rejected because the circuit reached its concurrency limit
+func (e *CircuitWrapperAggregatorError) ConcurrencyLimitReached() bool {
+	return e.concurrencyLimitReached
+}
+
+// Timeout returns true if the call exceeded the circuit's timeout
+func (e *CircuitWrapperAggregatorError) Timeout() bool {
+	return e.timeout
+}
+
+// circuitWrapperAggregatorKeyedCircuits lazily creates the keyed circuits of a method of CircuitWrapperAggregator on the manager.
+// The manager can't remove circuits, so calls with new keys use the circuit of the method once there are maxKeys
+type circuitWrapperAggregatorKeyedCircuits struct {
+	manager *circuit.Manager
+	name    string
+	maxKeys int
+	configs []circuit.Config
+
+	mu       sync.Mutex
+	circuits map[string]*circuit.Circuit
+}
+
+func newCircuitWrapperAggregatorKeyedCircuits(manager *circuit.Manager, name string, maxKeys int, configs ...circuit.Config) *circuitWrapperAggregatorKeyedCircuits {
+	return &circuitWrapperAggregatorKeyedCircuits{
+		manager:  manager,
+		name:     name,
+		maxKeys:  maxKeys,
+		configs:  configs,
+		circuits: make(map[string]*circuit.Circuit),
+	}
+}
+
+// get returns the circuit of the key, creating it if needed. The default circuit is returned for an empty key or once
+// there are maxKeys circuits
+func (k *circuitWrapperAggregatorKeyedCircuits) get(key string, defaultCircuit *circuit.Circuit) (*circuit.Circuit, error) {
+	if key == "" {
+		return defaultCircuit, nil
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if c, ok := k.circuits[key]; ok {
+		return c, nil
+	}
+	if len(k.circuits) >= k.maxKeys {
+		return defaultCircuit, nil
+	}
+
+	name := k.name + "." + key
+	c, err := k.manager.CreateCircuit(name, k.configs...)
+	if err != nil {
+		return nil, fmt.Errorf("creating keyed circuit %s: %w", name, err)
+	}
+	k.circuits[key] = c
+	return c, nil
+}
+
+// CircuitWrapperAggregatorPanicError is returned by CircuitWrapperAggregator when an embedded method panics and
+// RecoverPanics is set
+type CircuitWrapperAggregatorPanicError struct {
+	// Method is the name of the wrapped method
+	Method string
+
+	// Value is the value the method panicked with
+	Value interface{}
+
+	// Stack is the stack trace of the panicking goroutine
+	Stack []byte
+}
+
+func (e *CircuitWrapperAggregatorPanicError) Error() string {
+	return "panic in " + e.Method + ": " + fmt.Sprint(e.Value)
+}
+
+// Unwrap returns the value the method panicked with if it is an error
+func (e *CircuitWrapperAggregatorPanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// recoverPanic recovers a panic of the embedded method into the error of the call if RecoverPanics is set. It must be
+// deferred by the call
+func (w *CircuitWrapperAggregator) recoverPanic(method string, err *error) {
+	if !w.RecoverPanics {
+		return
+	}
+
+	if r := recover(); r != nil {
+		*err = &CircuitWrapperAggregatorPanicError{Method: method, Value: r, Stack: debug.Stack()}
+	}
+}
+
+// repanic panics with the CircuitWrapperAggregatorPanicError of a call if Repanic is set
+func (w *CircuitWrapperAggregator) repanic(err error) {
+	var perr *CircuitWrapperAggregatorPanicError
+	if w.Repanic && errors.As(err, &perr) {
+		panic(perr)
+	}
+}
+
+// bypassed returns whether a call bypasses the circuits, and reports it to OnBypass
+func (w *CircuitWrapperAggregator) bypassed(ctx context.Context, method string) bool {
+	if w.Bypass == nil || !w.Bypass(ctx) {
+		return false
+	}
+
+	if w.OnBypass != nil {
+		w.OnBypass(method)
+	}
+	return true
+}
+
+// isCanceledBadRequest returns whether the error of a call is counted as a bad request because the caller's context is
+// done. The circuit's timeout doesn't end the caller's context
+func (w *CircuitWrapperAggregator) isCanceledBadRequest(callerCtx context.Context, err error) bool {
+	return w.CanceledAsBadRequest && err != nil && callerCtx.Err() != nil
+}
+
+// circuitError wraps the error of a call in a CircuitWrapperAggregatorError if the circuit rejected the call, or if it
+// failed with context.DeadlineExceeded while the caller's context isn't done
+func (w *CircuitWrapperAggregator) circuitError(ctx context.Context, c *circuit.Circuit, method string, err error) error {
+	var berr *circuit.SimpleBadRequest
+	if err == nil || errors.As(err, &berr) {
+		return err
+	}
+
+	if cerr, ok := err.(interface {
+		CircuitOpen() bool
+		ConcurrencyLimitReached() bool
+	}); ok {
+		return &CircuitWrapperAggregatorError{
+			Circuit:                 c.Name(),
+			Method:                  method,
+			Err:                     err,
+			circuitOpen:             cerr.CircuitOpen(),
+			concurrencyLimitReached: cerr.ConcurrencyLimitReached(),
+		}
+	}
+
+	if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+		return &CircuitWrapperAggregatorError{Circuit: c.Name(), Method: method, Err: err, timeout: true}
+	}
+
+	return err
+}
+
+// run executes the circuit with the policy. Calls are attempted once if the policy is nil
+func (p *CircuitWrapperAggregatorRetryPolicy) run(ctx context.Context, c *circuit.Circuit, runFunc func(context.Context) error, fallbackFunc func(context.Context, error) error) error {
+	if p == nil || p.MaxAttempts < 2 {
+		return c.Execute(ctx, runFunc, fallbackFunc)
+	}
+
+	if p.AroundCircuit {
+		// Falling back on every attempt would stop the retries, so the fallback only gets the error of the last one
+		err := p.retry(ctx, func(ctx context.Context) error {
+			return c.Execute(ctx, runFunc, nil)
+		})
+		if err != nil && fallbackFunc != nil && !circuit.IsBadRequest(err) {
+			return fallbackFunc(ctx, err)
+		}
+		return err
+	}
+
+	return c.Execute(ctx, func(ctx context.Context) error {
+		return p.retry(ctx, runFunc)
+	}, fallbackFunc)
+}
+
+// retry calls f until it succeeds, its error isn't retried, the attempts run out, or the context would be done
+// before the next attempt
+func (p *CircuitWrapperAggregatorRetryPolicy) retry(ctx context.Context, f func(context.Context) error) error {
+	backoff := p.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := f(ctx)
+		if err == nil || attempt >= p.MaxAttempts || !p.isRetried(err) {
+			return err
+		}
+
+		wait := backoff
+		if p.Jitter > 0 {
+			wait -= time.Duration(p.Jitter * rand.Float64() * float64(backoff)) // #nosec G404 jitter doesn't need a secure random
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+	}
+}
+
+// isRetried returns whether the error of an attempt is retried
+func (p *CircuitWrapperAggregatorRetryPolicy) isRetried(err error) bool {
+	var berr *circuit.SimpleBadRequest
+	if errors.As(err, &berr) {
+		return false
+	}
+	if cerr, ok := err.(interface{ CircuitOpen() bool }); ok && cerr.CircuitOpen() {
+		return false
+	}
+	var perr *CircuitWrapperAggregatorPanicError
+	if errors.As(err, &perr) {
+		return false
+	}
+	return p.ShouldRetry == nil || p.ShouldRetry(err)
+}
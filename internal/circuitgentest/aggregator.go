@@ -17,18 +17,31 @@ import (
 	"context"
 )
 
-// Aggregator is a test struct for wrapper generation
+// Aggregator is a test struct for wrapper generation. Its wrapper in circuittest is generated by the scan command
+//
+//circuitgen:wrap out=./circuittest
 type Aggregator struct {
 	IncSumError error
+	ResetError  error
 	sum         int
 }
 
 // IncSum increments sum by v
+//
+//circuitgen:timeout=200ms
 func (a *Aggregator) IncSum(ctx context.Context, v int) error {
 	a.sum += v
 	return a.IncSumError
 }
 
+// Reset sets sum to zero. It does not accept a context and is wrapped by opting in with a directive
+//
+//circuitgen:wrap-without-context
+func (a *Aggregator) Reset() error {
+	a.sum = 0
+	return a.ResetError
+}
+
 // Sum returns sum
 func (a *Aggregator) Sum() int {
 	return a.sum
@@ -19,4 +19,5 @@ package circuittest
 
 //go:generate circuitgen circuit --goimports=true --pkg ../ --name Publisher --out ./
 //go:generate circuitgen circuit --goimports=true --pkg ../ --name Publisher --alias Pubsub --out ./pubsub.gen.go
-//go:generate circuitgen circuit --goimports=true --pkg ../ --name Aggregator --out ./aggregator.gen.go
+//go:generate circuitgen --goimports=true --config circuitgen.yaml
+//go:generate circuitgen scan --goimports=true ../
@@ -16,6 +16,8 @@ package circuittest
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -23,6 +25,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"github.com/twitchtv/circuitgen/circuitbypass"
 	"github.com/twitchtv/circuitgen/internal/circuitgentest"
 	"github.com/twitchtv/circuitgen/internal/circuitgentest/model"
 	"github.com/twitchtv/circuitgen/internal/circuitgentest/rep"
@@ -206,6 +209,628 @@ func TestPublisherInterfaceSkippedError(t *testing.T) {
 	assert.EqualValues(t, 0, publishCounter.badRequest)
 }
 
+func TestPublisherInterfaceFallback(t *testing.T) {
+	manager := &circuit.Manager{}
+
+	testError := errors.New("test error")
+	badRequestError := errors.New("bad request error")
+	publishInput := rep.PublishInput{UserID: "9999"}
+	fallbackResult := &model.Result{Nonce: "fallback"}
+	m := &circuitgentest.MockPublisher{}
+	m.On("PublishWithResult", mock.Anything, publishInput).Return(nil, testError).Once()
+	m.On("PublishWithResult", mock.Anything, publishInput).Return(nil, badRequestError).Once()
+
+	opt := rep.PublishOption{Sample: 0.1}
+	m.On("Publish", mock.Anything, mock.Anything, mock.Anything, opt).Return(nil, testError).Once()
+
+	var fallbackErrs []error
+	var fallbackOpts []rep.PublishOption
+	publishWithResultCounter := &runMetricsCounter{}
+	publisher, err := NewCircuitWrapperPublisher(manager, m, CircuitWrapperPublisherConfig{
+		IsBadRequest: func(err error) bool {
+			return err == badRequestError
+		},
+		CircuitPublishWithResult: circuit.Config{
+			Metrics: circuit.MetricsCollectors{
+				Run: []circuit.RunMetrics{publishWithResultCounter},
+			},
+		},
+		FallbackPublishWithResult: func(ctx context.Context, input rep.PublishInput, err error) (*model.Result, error) {
+			require.Equal(t, publishInput, input)
+			fallbackErrs = append(fallbackErrs, err)
+			return fallbackResult, nil
+		},
+		FallbackPublish: func(ctx context.Context, g map[circuitgentest.Seed][][]circuitgentest.Grant, s circuitgentest.TopicsList, opts []rep.PublishOption, err error) (map[string]struct{}, error) {
+			fallbackOpts = opts
+			return nil, err
+		},
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	// The fallback results are returned instead of the error
+	res, err := publisher.PublishWithResult(ctx, publishInput)
+	require.NoError(t, err)
+	require.Equal(t, fallbackResult, res)
+	require.Equal(t, []error{testError}, fallbackErrs)
+	assert.EqualValues(t, 1, publishWithResultCounter.failure)
+
+	// Bad requests don't fall back
+	res, err = publisher.PublishWithResult(ctx, publishInput)
+	require.Equal(t, badRequestError, err)
+	require.Nil(t, res)
+	require.Len(t, fallbackErrs, 1)
+
+	// Variadic params are passed to the fallback as a slice, and the fallback error is returned
+	_, err = publisher.Publish(ctx, nil, circuitgentest.TopicsList{}, opt)
+	require.Equal(t, testError, err)
+	require.Equal(t, []rep.PublishOption{opt}, fallbackOpts)
+
+	m.AssertExpectations(t)
+}
+
+func TestPublisherInterfaceRetry(t *testing.T) {
+	manager := &circuit.Manager{}
+
+	testError := errors.New("test error")
+	badRequestError := errors.New("bad request error")
+	publishInput := rep.PublishInput{UserID: "9999"}
+	publishResult := &model.Result{Nonce: "abcdefg"}
+	m := &circuitgentest.MockPublisher{}
+	m.On("PublishWithResult", mock.Anything, publishInput).Return(nil, testError).Twice()
+	m.On("PublishWithResult", mock.Anything, publishInput).Return(publishResult, nil).Once()
+	m.On("PublishWithResult", mock.Anything, publishInput).Return(nil, badRequestError).Once()
+	m.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil, testError).Twice()
+
+	publishCounter := &runMetricsCounter{}
+	publishWithResultCounter := &runMetricsCounter{}
+	publisher, err := NewCircuitWrapperPublisher(manager, m, CircuitWrapperPublisherConfig{
+		IsBadRequest: func(err error) bool {
+			return err == badRequestError
+		},
+		Retry: &CircuitWrapperPublisherRetryPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: time.Millisecond,
+			Jitter:         0.5,
+		},
+		RetryPublish: &CircuitWrapperPublisherRetryPolicy{
+			MaxAttempts:   2,
+			AroundCircuit: true,
+			ShouldRetry: func(err error) bool {
+				return err == testError
+			},
+		},
+		CircuitPublish: circuit.Config{
+			Metrics: circuit.MetricsCollectors{
+				Run: []circuit.RunMetrics{publishCounter},
+			},
+		},
+		CircuitPublishWithResult: circuit.Config{
+			Metrics: circuit.MetricsCollectors{
+				Run: []circuit.RunMetrics{publishWithResultCounter},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	// Retries inside the circuit are tracked as one call
+	res, err := publisher.PublishWithResult(ctx, publishInput)
+	require.NoError(t, err)
+	require.Equal(t, publishResult, res)
+	assert.EqualValues(t, 1, publishWithResultCounter.success)
+	assert.EqualValues(t, 0, publishWithResultCounter.failure)
+
+	// Bad requests are never retried
+	_, err = publisher.PublishWithResult(ctx, publishInput)
+	require.Equal(t, badRequestError, err)
+	assert.EqualValues(t, 1, publishWithResultCounter.badRequest)
+
+	// Retries around the circuit track every attempt
+	_, err = publisher.Publish(ctx, nil, circuitgentest.TopicsList{})
+	require.Equal(t, testError, err)
+	assert.EqualValues(t, 2, publishCounter.failure)
+
+	m.AssertExpectations(t)
+}
+
+func TestPublisherInterfaceRetryFallback(t *testing.T) {
+	manager := &circuit.Manager{}
+
+	testError := errors.New("test error")
+	publishInput := rep.PublishInput{UserID: "9999"}
+	fallbackResult := &model.Result{Nonce: "fallback"}
+	m := &circuitgentest.MockPublisher{}
+	m.On("PublishWithResult", mock.Anything, publishInput).Return(nil, testError).Times(3)
+
+	var fallbackErrs []error
+	publishWithResultCounter := &runMetricsCounter{}
+	publisher, err := NewCircuitWrapperPublisher(manager, m, CircuitWrapperPublisherConfig{
+		RetryPublishWithResult: &CircuitWrapperPublisherRetryPolicy{
+			MaxAttempts:   3,
+			AroundCircuit: true,
+		},
+		CircuitPublishWithResult: circuit.Config{
+			Metrics: circuit.MetricsCollectors{
+				Run: []circuit.RunMetrics{publishWithResultCounter},
+			},
+		},
+		FallbackPublishWithResult: func(ctx context.Context, input rep.PublishInput, err error) (*model.Result, error) {
+			fallbackErrs = append(fallbackErrs, err)
+			return fallbackResult, nil
+		},
+	})
+	require.NoError(t, err)
+
+	// The fallback doesn't stop the retries around the circuit, and is called once they run out
+	res, err := publisher.PublishWithResult(context.Background(), publishInput)
+	require.NoError(t, err)
+	require.Equal(t, fallbackResult, res)
+	require.Equal(t, []error{testError}, fallbackErrs)
+	assert.EqualValues(t, 3, publishWithResultCounter.failure)
+
+	m.AssertExpectations(t)
+}
+
+func TestPublisherInterfaceRetryDeadline(t *testing.T) {
+	manager := &circuit.Manager{}
+
+	testError := errors.New("test error")
+	m := &circuitgentest.MockPublisher{}
+	m.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil, testError).Once()
+
+	publisher, err := NewCircuitWrapperPublisher(manager, m, CircuitWrapperPublisherConfig{
+		Retry: &CircuitWrapperPublisherRetryPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: time.Hour,
+		},
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// The backoff would exceed the deadline, so the call isn't retried
+	start := time.Now()
+	_, err = publisher.Publish(ctx, nil, circuitgentest.TopicsList{})
+	require.Equal(t, testError, err)
+	require.True(t, time.Since(start) < time.Second)
+
+	m.AssertExpectations(t)
+}
+
+func TestPublisherHedged(t *testing.T) {
+	manager := &circuit.Manager{}
+
+	publishInput := rep.PublishInput{UserID: "9999"}
+	publishResult := &model.Result{Nonce: "abcdefg"}
+	m := &circuitgentest.MockPublisher{}
+	// The first call is slow and only returns once cancelled
+	m.On("PublishWithResult", mock.Anything, publishInput).Run(waitForCancel(t)).Return(nil, context.Canceled).Once()
+	m.On("PublishWithResult", mock.Anything, publishInput).Return(publishResult, nil).Once()
+	m.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil).Once()
+
+	publishWithResultCounter := &syncRunMetricsCounter{}
+	publisher, err := NewCircuitWrapperPublisherHedged(manager, m, CircuitWrapperPublisherHedgedConfig{
+		HedgePublishWithResult: time.Millisecond,
+		CircuitPublishWithResult: circuit.Config{
+			Metrics: circuit.MetricsCollectors{
+				Run: []circuit.RunMetrics{publishWithResultCounter},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	// The hedged call wins and the first is cancelled
+	res, err := publisher.PublishWithResult(ctx, publishInput)
+	require.NoError(t, err)
+	require.Equal(t, publishResult, res)
+
+	// Both calls are tracked by the circuit. The cancelled call is an interrupt, not a failure. It finishes
+	// asynchronously
+	counts := publishWithResultCounter.counts()
+	for start := time.Now(); counts.interrupt == 0 && time.Since(start) < time.Second; counts = publishWithResultCounter.counts() {
+		time.Sleep(time.Millisecond)
+	}
+	assert.EqualValues(t, 1, counts.success)
+	assert.EqualValues(t, 1, counts.interrupt)
+	assert.EqualValues(t, 0, counts.failure)
+
+	// Hedging is off unless a delay is set
+	_, err = publisher.Publish(ctx, nil, circuitgentest.TopicsList{})
+	require.NoError(t, err)
+
+	m.AssertExpectations(t)
+}
+
+func TestPublisherHedgedPanic(t *testing.T) {
+	manager := &circuit.Manager{}
+
+	publishInput := rep.PublishInput{UserID: "9999"}
+	m := &circuitgentest.MockPublisher{}
+	// The first call is slow and only returns once cancelled, and the hedged call panics
+	m.On("PublishWithResult", mock.Anything, publishInput).Run(waitForCancel(t)).Return(nil, context.Canceled).Once()
+	m.On("PublishWithResult", mock.Anything, publishInput).Run(func(args mock.Arguments) {
+		panic("hedged panic")
+	}).Once()
+
+	publisher, err := NewCircuitWrapperPublisherHedged(manager, m, CircuitWrapperPublisherHedgedConfig{
+		HedgePublishWithResult: time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	// The panic of the hedged call reaches the caller
+	require.PanicsWithValue(t, "hedged panic", func() {
+		res, err := publisher.PublishWithResult(context.Background(), publishInput)
+		t.Errorf("expected a panic, got %v, %v", res, err)
+	})
+
+	m.AssertExpectations(t)
+}
+
+func TestPublisherInterfaceCircuitErrors(t *testing.T) {
+	manager := &circuit.Manager{}
+
+	m := &circuitgentest.MockPublisher{}
+	m.On("Publish", mock.Anything, mock.Anything, mock.Anything).Run(waitForCancel(t)).Return(nil, context.DeadlineExceeded).Once()
+
+	publisher, err := NewCircuitWrapperPublisher(manager, m, CircuitWrapperPublisherConfig{
+		Prefix: "test.",
+		CircuitPublish: circuit.Config{
+			Execution: circuit.ExecutionConfig{
+				Timeout: time.Millisecond,
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	// Open circuits are not called
+	publisher.CircuitPublishWithResult.OpenCircuit()
+	_, err = publisher.PublishWithResult(ctx, rep.PublishInput{})
+	var cerr *CircuitWrapperPublisherError
+	require.True(t, errors.As(err, &cerr))
+	require.Equal(t, "test.Publisher.PublishWithResult", cerr.Circuit)
+	require.Equal(t, "PublishWithResult", cerr.Method)
+	require.True(t, cerr.CircuitOpen())
+	require.False(t, cerr.Timeout())
+
+	// Timeouts wrap the error of the method
+	_, err = publisher.Publish(ctx, nil, circuitgentest.TopicsList{})
+	require.True(t, errors.As(err, &cerr))
+	require.Equal(t, "Publish", cerr.Method)
+	require.True(t, cerr.Timeout())
+	require.False(t, cerr.CircuitOpen())
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	m.AssertExpectations(t)
+}
+
+func TestPublisherInterfaceWrappedErrors(t *testing.T) {
+	manager := &circuit.Manager{}
+
+	badRequestError := errors.New("bad request error")
+	skippedError := errors.New("skipped error")
+	wrappedBadRequest := fmt.Errorf("publish: %w", fmt.Errorf("validating input: %w", badRequestError))
+	wrappedSkipped := fmt.Errorf("publish: %w", skippedError)
+	m := &circuitgentest.MockPublisher{}
+	m.On("PublishWithResult", mock.Anything, mock.Anything).Return(nil, wrappedBadRequest).Once()
+	m.On("PublishWithResult", mock.Anything, mock.Anything).Return(nil, wrappedSkipped).Once()
+	// The embedded implementation may wrap bad requests itself
+	m.On("PublishWithResult", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("publish: %w", &circuit.SimpleBadRequest{Err: badRequestError})).Once()
+
+	publishWithResultCounter := &runMetricsCounter{}
+	publisher, err := NewCircuitWrapperPublisher(manager, m, CircuitWrapperPublisherConfig{
+		ShouldSkipError: func(err error) bool {
+			return errors.Is(err, skippedError)
+		},
+		IsBadRequest: func(err error) bool {
+			return errors.Is(err, badRequestError)
+		},
+		CircuitPublishWithResult: circuit.Config{
+			Metrics: circuit.MetricsCollectors{
+				Run: []circuit.RunMetrics{publishWithResultCounter},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	// The whole chain is returned, not the circuit's bad request
+	_, err = publisher.PublishWithResult(ctx, rep.PublishInput{})
+	require.Equal(t, wrappedBadRequest, err)
+	require.True(t, errors.Is(err, badRequestError))
+	assert.EqualValues(t, 1, publishWithResultCounter.badRequest)
+
+	_, err = publisher.PublishWithResult(ctx, rep.PublishInput{})
+	require.Equal(t, wrappedSkipped, err)
+	assert.EqualValues(t, 1, publishWithResultCounter.success)
+
+	// Bad requests wrapped in the chain are unwrapped with errors.As
+	_, err = publisher.PublishWithResult(ctx, rep.PublishInput{})
+	require.Equal(t, badRequestError, err)
+
+	m.AssertExpectations(t)
+}
+
+func TestPublisherInterfaceMethodErrorClassifiers(t *testing.T) {
+	manager := &circuit.Manager{}
+
+	notFoundError := errors.New("not found")
+	m := &circuitgentest.MockPublisher{}
+	m.On("PublishWithResult", mock.Anything, mock.Anything).Return(nil, notFoundError).Times(3)
+	m.On("Publish", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, notFoundError).Once()
+
+	publishWithResultCounter := &runMetricsCounter{}
+	publishCounter := &runMetricsCounter{}
+	publisher, err := NewCircuitWrapperPublisher(manager, m, CircuitWrapperPublisherConfig{
+		IsBadRequest: func(err error) bool {
+			return errors.Is(err, notFoundError)
+		},
+		// Not found is only a success for a user's lookups
+		ShouldSkipErrorPublishWithResult: func(ctx context.Context, input rep.PublishInput, err error) bool {
+			return input.UserID != "" && errors.Is(err, notFoundError)
+		},
+		// Not found is a failure for publishing
+		IsBadRequestPublish: func(ctx context.Context, g map[circuitgentest.Seed][][]circuitgentest.Grant, s circuitgentest.TopicsList, opts []rep.PublishOption, err error) bool {
+			return false
+		},
+		CircuitPublishWithResult: circuit.Config{
+			Metrics: circuit.MetricsCollectors{
+				Run: []circuit.RunMetrics{publishWithResultCounter},
+			},
+		},
+		CircuitPublish: circuit.Config{
+			Metrics: circuit.MetricsCollectors{
+				Run: []circuit.RunMetrics{publishCounter},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	_, err = publisher.Publish(ctx, nil, circuitgentest.TopicsList{}, rep.PublishOption{})
+	require.Equal(t, notFoundError, err)
+	assert.EqualValues(t, 1, publishCounter.failure)
+	assert.EqualValues(t, 0, publishCounter.badRequest)
+
+	_, err = publisher.PublishWithResult(ctx, rep.PublishInput{UserID: "user"})
+	require.Equal(t, notFoundError, err)
+	assert.EqualValues(t, 1, publishWithResultCounter.success)
+
+	// Falls back to the wrapper's IsBadRequest
+	_, err = publisher.PublishWithResult(ctx, rep.PublishInput{})
+	require.Equal(t, notFoundError, err)
+	assert.EqualValues(t, 1, publishWithResultCounter.badRequest)
+
+	// The wrapper's checkers can be replaced after construction
+	publisher.IsBadRequest = func(err error) bool {
+		return false
+	}
+	_, err = publisher.PublishWithResult(ctx, rep.PublishInput{})
+	require.Equal(t, notFoundError, err)
+	assert.EqualValues(t, 1, publishWithResultCounter.failure)
+
+	m.AssertExpectations(t)
+}
+
+func TestPublisherInterfacePanics(t *testing.T) {
+	manager := &circuit.Manager{}
+
+	m := &circuitgentest.MockPublisher{}
+	m.On("PublishWithResult", mock.Anything, mock.Anything).Return(nil, nil).Run(func(args mock.Arguments) {
+		panic("embedded panic")
+	}).Times(3)
+
+	publishWithResultCounter := &runMetricsCounter{}
+	publisher, err := NewCircuitWrapperPublisher(manager, m, CircuitWrapperPublisherConfig{
+		RecoverPanics: true,
+		// Panics are never retried
+		Retry: &CircuitWrapperPublisherRetryPolicy{MaxAttempts: 3},
+		CircuitPublishWithResult: circuit.Config{
+			Metrics: circuit.MetricsCollectors{
+				Run: []circuit.RunMetrics{publishWithResultCounter},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	_, err = publisher.PublishWithResult(ctx, rep.PublishInput{})
+	var perr *CircuitWrapperPublisherPanicError
+	require.True(t, errors.As(err, &perr))
+	assert.Equal(t, "PublishWithResult", perr.Method)
+	assert.Equal(t, "embedded panic", perr.Value)
+	assert.Contains(t, string(perr.Stack), "MockPublisher")
+	assert.EqualValues(t, 1, publishWithResultCounter.failure)
+
+	// The panic is counted before panicking again
+	publisher.Repanic = true
+	r := func() (r interface{}) {
+		defer func() {
+			r = recover()
+		}()
+		res, err := publisher.PublishWithResult(ctx, rep.PublishInput{})
+		t.Errorf("expected a panic, got %v, %v", res, err)
+		return nil
+	}()
+	require.IsType(t, &CircuitWrapperPublisherPanicError{}, r)
+	assert.EqualValues(t, 2, publishWithResultCounter.failure)
+
+	publisher.RecoverPanics = false
+	require.Panics(t, func() {
+		res, err := publisher.PublishWithResult(ctx, rep.PublishInput{})
+		t.Errorf("expected a panic, got %v, %v", res, err)
+	})
+	assert.EqualValues(t, 2, publishWithResultCounter.failure)
+
+	m.AssertExpectations(t)
+}
+
+func TestPublisherInterfaceCanceled(t *testing.T) {
+	m := &circuitgentest.MockPublisher{}
+	m.On("PublishWithResult", mock.Anything, mock.Anything).Return(nil, context.Canceled).Twice()
+
+	fallbackCalls := 0
+	newPublisher := func(conf CircuitWrapperPublisherConfig) (*CircuitWrapperPublisher, *runMetricsCounter) {
+		counter := &runMetricsCounter{}
+		conf.CircuitPublishWithResult.Metrics.Run = []circuit.RunMetrics{counter}
+		conf.FallbackPublishWithResult = func(ctx context.Context, input rep.PublishInput, err error) (*model.Result, error) {
+			fallbackCalls++
+			return nil, err
+		}
+		publisher, err := NewCircuitWrapperPublisher(&circuit.Manager{}, m, conf)
+		require.NoError(t, err)
+		return publisher, counter
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Counted as an interrupt by default
+	publisher, counter := newPublisher(CircuitWrapperPublisherConfig{})
+	_, err := publisher.PublishWithResult(ctx, rep.PublishInput{})
+	require.Equal(t, context.Canceled, err)
+	assert.EqualValues(t, 1, counter.interrupt)
+	assert.EqualValues(t, 0, counter.failure)
+
+	// Counted as a bad request, even if the circuit ignores interrupts
+	publisher, counter = newPublisher(CircuitWrapperPublisherConfig{
+		CanceledAsBadRequest: true,
+		CircuitPublishWithResult: circuit.Config{
+			Execution: circuit.ExecutionConfig{IgnoreInterrputs: true},
+		},
+	})
+	_, err = publisher.PublishWithResult(ctx, rep.PublishInput{})
+	require.Equal(t, context.Canceled, err)
+	assert.EqualValues(t, 1, counter.badRequest)
+	assert.EqualValues(t, 0, counter.failure)
+	// Only the interrupt falls back
+	assert.Equal(t, 1, fallbackCalls)
+
+	m.AssertExpectations(t)
+}
+
+func TestPublisherInterfaceBypass(t *testing.T) {
+	manager := &circuit.Manager{}
+
+	m := &circuitgentest.MockPublisher{}
+	m.On("PublishWithResult", mock.Anything, mock.Anything).Return(&model.Result{}, nil).Once()
+
+	var bypassedMethods []string
+	publishWithResultCounter := &runMetricsCounter{}
+	publisher, err := NewCircuitWrapperPublisher(manager, m, CircuitWrapperPublisherConfig{
+		Bypass: circuitbypass.IsBypassed,
+		OnBypass: func(method string) {
+			bypassedMethods = append(bypassedMethods, method)
+		},
+		CircuitPublishWithResult: circuit.Config{
+			Metrics: circuit.MetricsCollectors{
+				Run: []circuit.RunMetrics{publishWithResultCounter},
+			},
+		},
+	})
+	require.NoError(t, err)
+	publisher.CircuitPublishWithResult.OpenCircuit()
+
+	ctx := context.Background()
+
+	_, err = publisher.PublishWithResult(ctx, rep.PublishInput{})
+	var cerr *CircuitWrapperPublisherError
+	require.True(t, errors.As(err, &cerr))
+	require.True(t, cerr.CircuitOpen())
+
+	// Not short-circuited and not tracked by the circuit
+	result, err := publisher.PublishWithResult(circuitbypass.WithCircuitBypass(ctx), rep.PublishInput{})
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	assert.Equal(t, []string{"PublishWithResult"}, bypassedMethods)
+	assert.EqualValues(t, 1, publishWithResultCounter.shortCircuit)
+	assert.EqualValues(t, 0, publishWithResultCounter.success)
+
+	m.AssertExpectations(t)
+}
+
+func TestPublisherInterfaceKeyedCircuits(t *testing.T) {
+	counters := make(map[string]*runMetricsCounter)
+	var created []string
+	manager := &circuit.Manager{
+		DefaultCircuitProperties: []circuit.CommandPropertiesConstructor{
+			func(circuitName string) circuit.Config {
+				created = append(created, circuitName)
+				counters[circuitName] = &runMetricsCounter{}
+				return circuit.Config{
+					Metrics: circuit.MetricsCollectors{
+						Run: []circuit.RunMetrics{counters[circuitName]},
+					},
+				}
+			},
+		},
+	}
+
+	m := &circuitgentest.MockPublisher{}
+	m.On("PublishWithResult", mock.Anything, mock.Anything).Return(nil, nil).Times(6)
+
+	publisher, err := NewCircuitWrapperPublisher(manager, m, CircuitWrapperPublisherConfig{
+		Prefix:  "test.",
+		MaxKeys: 2,
+		KeyPublishWithResult: func(ctx context.Context, input rep.PublishInput) string {
+			return input.UserID
+		},
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	for _, userID := range []string{"a", "b", "a", "c", "b", ""} {
+		_, err = publisher.PublishWithResult(ctx, rep.PublishInput{UserID: userID})
+		require.NoError(t, err)
+	}
+
+	// "c" exceeds MaxKeys
+	assert.Equal(t, []string{
+		"test.Publisher.PublishWithResult.a",
+		"test.Publisher.PublishWithResult.b",
+	}, created[len(created)-2:])
+	assert.EqualValues(t, 2, counters["test.Publisher.PublishWithResult.a"].success)
+	assert.EqualValues(t, 2, counters["test.Publisher.PublishWithResult.b"].success)
+	// Calls without a key or exceeding MaxKeys use the method's circuit
+	assert.EqualValues(t, 2, counters["test.Publisher.PublishWithResult"].success)
+	// Keyed circuits are added to the manager
+	names := circuitNames(manager)
+	assert.Contains(t, names, "test.Publisher.PublishWithResult.a")
+	assert.Contains(t, names, "test.Publisher.PublishWithResult.b")
+	assert.NotContains(t, names, "test.Publisher.PublishWithResult.c")
+
+	m.AssertExpectations(t)
+}
+
+func TestPublisherInterfaceKeyedCircuitExists(t *testing.T) {
+	manager := &circuit.Manager{}
+	manager.MustCreateCircuit("Publisher.PublishWithResult.a")
+
+	m := &circuitgentest.MockPublisher{}
+
+	publisher, err := NewCircuitWrapperPublisher(manager, m, CircuitWrapperPublisherConfig{
+		KeyPublishWithResult: func(ctx context.Context, input rep.PublishInput) string {
+			return input.UserID
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = publisher.PublishWithResult(context.Background(), rep.PublishInput{UserID: "a"})
+	require.Error(t, err)
+
+	m.AssertExpectations(t)
+}
+
 // Thinner test of aliased wrapper.
 func TestPubsubInterface(t *testing.T) {
 	manager := &circuit.Manager{}
@@ -262,6 +887,233 @@ func TestAggregatorStruct(t *testing.T) {
 	err = wrapperAgg.IncSum(context.Background(), 10)
 	require.Equal(t, sumErr, err)
 
+	// Reset opts in to being wrapped without a context with a directive
+	require.NotNil(t, manager.GetCircuit("Aggregator.Reset"))
+	require.NoError(t, wrapperAgg.Reset())
+	require.Equal(t, 0, agg.Sum())
+
+	// IncSum has a default timeout set with a directive, which configs override
+	require.Equal(t, 200*time.Millisecond, wrapperAgg.CircuitIncSum.Config().Execution.Timeout)
+	wrapperAgg, err = NewCircuitWrapperAggregator(&circuit.Manager{}, agg, CircuitWrapperAggregatorConfig{
+		CircuitIncSum: circuit.Config{
+			Execution: circuit.ExecutionConfig{Timeout: time.Second},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, time.Second, wrapperAgg.CircuitIncSum.Config().Execution.Timeout)
+}
+
+func TestPublisherWrappedWithoutContext(t *testing.T) {
+	manager := &circuit.Manager{}
+
+	closeErr := errors.New("close error")
+	m := &circuitgentest.MockPublisher{}
+	m.On("Close").Return(nil).Once()
+	m.On("Close").Return(closeErr).Once()
+
+	baseCtx, cancel := context.WithCancel(context.Background())
+	closeCounter := &runMetricsCounter{}
+	publisher, err := NewCircuitWrapperPublisherWithoutContext(manager, m, CircuitWrapperPublisherWithoutContextConfig{
+		BaseContext: func() context.Context {
+			return baseCtx
+		},
+		CircuitClose: circuit.Config{
+			Metrics: circuit.MetricsCollectors{
+				Run: []circuit.RunMetrics{closeCounter},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	// Check circuit names
+	names := circuitNames(manager)
+	require.Contains(t, names, "PublisherWithoutContext.Close")
+
+	require.NoError(t, publisher.Close())
+	require.Equal(t, 1, closeCounter.success)
+
+	// The circuit runs with the base context, so an error after it is cancelled is an interrupt
+	cancel()
+	require.Equal(t, closeErr, publisher.Close())
+	require.Equal(t, 1, closeCounter.interrupt)
+	require.Equal(t, 0, closeCounter.failure)
+
+	m.AssertExpectations(t)
+}
+
+func TestPublisherFiltered(t *testing.T) {
+	manager := &circuit.Manager{}
+
+	ctx := context.Background()
+	topics := circuitgentest.TopicsList{List: []string{"1234"}}
+	publishInput := rep.PublishInput{UserID: "9999"}
+	publishResult := &model.Result{Nonce: "abcdefg"}
+	m := &circuitgentest.MockPublisher{}
+	m.On("Publish", mock.Anything, mock.Anything, topics).Return(nil, nil).Once()
+	m.On("PublishWithResult", mock.Anything, publishInput).Return(publishResult, nil).Once()
+
+	publisher, err := NewCircuitWrapperPublisherFiltered(manager, m, CircuitWrapperPublisherFilteredConfig{})
+	require.NoError(t, err)
+
+	// Only included methods that aren't excluded have circuits
+	names := circuitNames(manager)
+	require.Equal(t, []string{"PublisherFiltered.Publish"}, names)
+
+	_, err = publisher.Publish(ctx, nil, topics)
+	require.NoError(t, err)
+
+	// Excluded methods pass through the embedded type
+	res, err := publisher.PublishWithResult(ctx, publishInput)
+	require.NoError(t, err)
+	require.Equal(t, publishResult, res)
+
+	m.AssertExpectations(t)
+}
+
+func TestStoreGeneric(t *testing.T) {
+	manager := &circuit.Manager{}
+	embedded := &circuitgentest.MapStore[string, int]{}
+
+	putCounter := &runMetricsCounter{}
+	store, err := NewCircuitWrapperStore[string, int](manager, embedded, CircuitWrapperStoreConfig[string, int]{
+		CircuitPut: circuit.Config{
+			Metrics: circuit.MetricsCollectors{
+				Run: []circuit.RunMetrics{putCounter},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	// Check circuit names
+	names := circuitNames(manager)
+	require.Contains(t, names, "Store.Get")
+	require.Contains(t, names, "Store.Put")
+
+	ctx := context.Background()
+	require.NoError(t, store.Put(ctx, "a", 1))
+	v, err := store.Get(ctx, "a")
+	require.NoError(t, err)
+	require.Equal(t, 1, v)
+	require.Equal(t, 1, store.Len())
+	require.Equal(t, 1, putCounter.success)
+
+	putErr := errors.New("put error")
+	embedded.PutError = putErr
+	require.Equal(t, putErr, store.Put(ctx, "b", 2))
+	require.Equal(t, 1, putCounter.failure)
+}
+
+func TestMapStoreGenericStruct(t *testing.T) {
+	manager := &circuit.Manager{}
+	embedded := &circuitgentest.MapStore[int, string]{}
+
+	store, err := NewCircuitWrapperMapStore(manager, embedded, CircuitWrapperMapStoreConfig[int, string]{})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, store.Put(ctx, 1, "a"))
+	v, err := store.Get(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, "a", v)
+	require.Equal(t, 1, embedded.Len())
+}
+
+func TestResultStoreInstantiated(t *testing.T) {
+	manager := &circuit.Manager{}
+	embedded := &circuitgentest.MapStore[string, *model.Result]{}
+
+	store, err := NewCircuitWrapperResultStore(manager, embedded, CircuitWrapperResultStoreConfig{})
+	require.NoError(t, err)
+
+	// Check circuit names
+	names := circuitNames(manager)
+	require.Contains(t, names, "ResultStore.Get")
+	require.Contains(t, names, "ResultStore.Put")
+
+	ctx := context.Background()
+	result := &model.Result{Nonce: "abcdefg"}
+	require.NoError(t, store.Put(ctx, "a", result))
+	res, err := store.Get(ctx, "a")
+	require.NoError(t, err)
+	require.Equal(t, result, res)
+}
+
+func TestFetcherContextNotFirstParam(t *testing.T) {
+	manager := &circuit.Manager{}
+
+	hasDeadline := mock.MatchedBy(func(ctx context.Context) bool {
+		_, ok := ctx.Deadline()
+		return ok
+	})
+	m := &circuitgentest.MockFetcher{}
+	m.On("FetchKey", "key", hasDeadline).Return("value", nil).Once()
+
+	fetchKeyCounter := &runMetricsCounter{}
+	fetcher, err := NewCircuitWrapperFetcher(manager, m, CircuitWrapperFetcherConfig{
+		CircuitFetchKey: circuit.Config{
+			Execution: circuit.ExecutionConfig{
+				Timeout: time.Second,
+			},
+			Metrics: circuit.MetricsCollectors{
+				Run: []circuit.RunMetrics{fetchKeyCounter},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	// Check circuit names
+	names := circuitNames(manager)
+	require.Contains(t, names, "Fetcher.FetchKey")
+	require.Contains(t, names, "Fetcher.Fetch")
+
+	// The circuit's context with the timeout is passed to the embedded method
+	v, err := fetcher.FetchKey("key", context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "value", v)
+	require.Equal(t, 1, fetchKeyCounter.success)
+
+	m.AssertExpectations(t)
+}
+
+func TestFetcherContextAccessor(t *testing.T) {
+	manager := &circuit.Manager{}
+
+	req := circuitgentest.NewFetchRequest(context.Background(), "key")
+	m := &circuitgentest.MockFetcher{}
+	m.On("Fetch", req).Return("value", nil).Once()
+
+	fetchCounter := &runMetricsCounter{}
+	fetcher, err := NewCircuitWrapperFetcher(manager, m, CircuitWrapperFetcherConfig{
+		CircuitFetch: circuit.Config{
+			Metrics: circuit.MetricsCollectors{
+				Run: []circuit.RunMetrics{fetchCounter},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	v, err := fetcher.Fetch(req)
+	require.NoError(t, err)
+	require.Equal(t, "value", v)
+	require.Equal(t, 1, fetchCounter.success)
+
+	// The embedded method is not called when the circuit is open
+	fetcher.CircuitFetch.OpenCircuit()
+	_, err = fetcher.Fetch(req)
+	require.Error(t, err)
+	require.Equal(t, 1, fetchCounter.shortCircuit)
+
+	m.AssertExpectations(t)
+}
+
+// waitForCancel returns a mock run func blocking the call until its context, the first param, is done
+func waitForCancel(t *testing.T) func(mock.Arguments) {
+	return func(args mock.Arguments) {
+		ctx, ok := args.Get(0).(context.Context)
+		if assert.True(t, ok, "expected a context as the first param") {
+			<-ctx.Done()
+		}
+	}
 }
 
 func circuitNames(m *circuit.Manager) []string {
@@ -291,3 +1143,60 @@ func (r *runMetricsCounter) ErrConcurrencyLimitReject(now time.Time)
 func (r *runMetricsCounter) ErrShortCircuit(now time.Time)                       { r.shortCircuit++ }
 
 var _ circuit.RunMetrics = (*runMetricsCounter)(nil)
+
+// syncRunMetricsCounter is a runMetricsCounter for circuits called concurrently
+type syncRunMetricsCounter struct {
+	mu sync.Mutex
+	c  runMetricsCounter
+}
+
+func (r *syncRunMetricsCounter) Success(now time.Time, duration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.c.Success(now, duration)
+}
+
+func (r *syncRunMetricsCounter) ErrFailure(now time.Time, duration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.c.ErrFailure(now, duration)
+}
+
+func (r *syncRunMetricsCounter) ErrTimeout(now time.Time, duration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.c.ErrTimeout(now, duration)
+}
+
+func (r *syncRunMetricsCounter) ErrBadRequest(now time.Time, duration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.c.ErrBadRequest(now, duration)
+}
+
+func (r *syncRunMetricsCounter) ErrInterrupt(now time.Time, duration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.c.ErrInterrupt(now, duration)
+}
+
+func (r *syncRunMetricsCounter) ErrConcurrencyLimitReject(now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.c.ErrConcurrencyLimitReject(now)
+}
+
+func (r *syncRunMetricsCounter) ErrShortCircuit(now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.c.ErrShortCircuit(now)
+}
+
+// counts returns a copy of the counts
+func (r *syncRunMetricsCounter) counts() runMetricsCounter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.c
+}
+
+var _ circuit.RunMetrics = (*syncRunMetricsCounter)(nil)
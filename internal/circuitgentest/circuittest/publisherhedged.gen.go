@@ -0,0 +1,713 @@
+// Code generated by circuitgen tool. DO NOT EDIT
+
+package circuittest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/cep21/circuit"
+	"github.com/twitchtv/circuitgen/internal/circuitgentest"
+	"github.com/twitchtv/circuitgen/internal/circuitgentest/model"
+	"github.com/twitchtv/circuitgen/internal/circuitgentest/rep"
+)
+
+// CircuitWrapperPublisherHedgedConfig contains configuration for CircuitWrapperPublisherHedged. All fields are optional
+type CircuitWrapperPublisherHedgedConfig struct {
+	// ShouldSkipError determines whether an error should be skipped and have the circuit
+	// track the call as successful. This takes precedence over IsBadRequest
+	ShouldSkipError func(error) bool
+
+	// IsBadRequest is an optional bad request checker. It is useful to not count user errors as faults
+	//
+	// Both checkers receive the error of the embedded method as is, so wrapped errors can be checked with errors.Is
+	// and errors.As
+	IsBadRequest func(error) bool
+
+	// Prefix is prepended to all circuit names
+	Prefix string
+
+	// Defaults are used for all created circuits. Per-circuit configs override this
+	Defaults circuit.Config
+
+	// MaxKeys bounds the number of keyed circuits of each method. Calls with new keys use the circuit of the method once
+	// it is reached. Defaults to 1000
+	MaxKeys int
+
+	// Retry is the retry policy of all methods. Calls are attempted once if nil
+	Retry *CircuitWrapperPublisherHedgedRetryPolicy
+
+	// RecoverPanics recovers panics of the embedded methods into a CircuitWrapperPublisherHedgedPanicError, which the
+	// circuit counts as a failure. Panics are not recovered by default
+	RecoverPanics bool
+
+	// Repanic panics again with the CircuitWrapperPublisherHedgedPanicError of a recovered panic after the circuit counted
+	// it, unless a fallback handled it. This has no effect without RecoverPanics
+	Repanic bool
+
+	// CanceledAsBadRequest counts calls failing after the caller's context is done as bad requests, which don't fall
+	// back and aren't retried. Otherwise the circuit counts them as interrupts, unless it ignores interrupts. Either
+	// way they don't count as failures, unlike the circuit's timeouts
+	CanceledAsBadRequest bool
+
+	// Bypass returns whether calls with the context bypass the circuits and call the embedded methods directly, ex.
+	// for admin tools. Bypassed calls aren't tracked by the circuits. Set it to circuitbypass.IsBypassed to bypass
+	// calls with contexts from circuitbypass.WithCircuitBypass. Calls aren't bypassed if nil
+	Bypass func(context.Context) bool
+
+	// OnBypass is called with the method name of every bypassed call if set, ex. to report metrics
+	OnBypass func(method string)
+
+	// CircuitPublish is the configuration used for the Publish circuit. This overrides values set by Defaults
+	CircuitPublish circuit.Config
+	// FallbackPublish is called with the params of Publish and the circuit error when the call fails or
+	// the circuit is open. Its results are returned instead. Bad requests and skipped errors don't fall back
+	FallbackPublish func(context.Context, map[circuitgentest.Seed][][]circuitgentest.Grant, circuitgentest.TopicsList, []rep.PublishOption, error) (map[string]struct{}, error)
+	// RetryPublish is the retry policy of Publish. This overrides Retry
+	RetryPublish *CircuitWrapperPublisherHedgedRetryPolicy
+	// KeyPublish returns the key of a call of Publish. Calls with a non-empty key use a circuit of the key
+	// named Prefix+"PublisherHedged.Publish.<key>", created on first use with CircuitPublish. Otherwise calls use
+	// CircuitPublish
+	KeyPublish func(context.Context, map[circuitgentest.Seed][][]circuitgentest.Grant, circuitgentest.TopicsList, []rep.PublishOption) string
+	// ShouldSkipErrorPublish overrides ShouldSkipError for Publish. It receives the params of the call
+	ShouldSkipErrorPublish func(context.Context, map[circuitgentest.Seed][][]circuitgentest.Grant, circuitgentest.TopicsList, []rep.PublishOption, error) bool
+	// IsBadRequestPublish overrides IsBadRequest for Publish. It receives the params of the call
+	IsBadRequestPublish func(context.Context, map[circuitgentest.Seed][][]circuitgentest.Grant, circuitgentest.TopicsList, []rep.PublishOption, error) bool
+	// HedgePublish is the delay after which a call of Publish that hasn't finished is hedged with a second
+	// call. The first to succeed is returned and the other is cancelled. Calls aren't hedged if not positive
+	HedgePublish time.Duration
+	// CircuitPublishWithResult is the configuration used for the PublishWithResult circuit. This overrides values set by Defaults
+	CircuitPublishWithResult circuit.Config
+	// FallbackPublishWithResult is called with the params of PublishWithResult and the circuit error when the call fails or
+	// the circuit is open. Its results are returned instead. Bad requests and skipped errors don't fall back
+	FallbackPublishWithResult func(context.Context, rep.PublishInput, error) (*model.Result, error)
+	// RetryPublishWithResult is the retry policy of PublishWithResult. This overrides Retry
+	RetryPublishWithResult *CircuitWrapperPublisherHedgedRetryPolicy
+	// KeyPublishWithResult returns the key of a call of PublishWithResult. Calls with a non-empty key use a circuit of the key
+	// named Prefix+"PublisherHedged.PublishWithResult.<key>", created on first use with CircuitPublishWithResult. Otherwise calls use
+	// CircuitPublishWithResult
+	KeyPublishWithResult func(context.Context, rep.PublishInput) string
+	// ShouldSkipErrorPublishWithResult overrides ShouldSkipError for PublishWithResult. It receives the params of the call
+	ShouldSkipErrorPublishWithResult func(context.Context, rep.PublishInput, error) bool
+	// IsBadRequestPublishWithResult overrides IsBadRequest for PublishWithResult. It receives the params of the call
+	IsBadRequestPublishWithResult func(context.Context, rep.PublishInput, error) bool
+	// HedgePublishWithResult is the delay after which a call of PublishWithResult that hasn't finished is hedged with a second
+	// call. The first to succeed is returned and the other is cancelled. Calls aren't hedged if not positive
+	HedgePublishWithResult time.Duration
+}
+
+// CircuitWrapperPublisherHedgedRetryPolicy configures retrying failed calls of CircuitWrapperPublisherHedged. Bad requests,
+// skipped errors, open circuits, and recovered panics are never retried
+type CircuitWrapperPublisherHedgedRetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts of a call, including the first. Calls are attempted once if less than 2
+	MaxAttempts int
+
+	// InitialBackoff is the backoff before the first retry. It doubles for every retry after
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the backoff between retries if set
+	MaxBackoff time.Duration
+
+	// Jitter is the fraction of each backoff that is randomly subtracted from it, between 0 and 1. 1 is full jitter
+	Jitter float64
+
+	// ShouldRetry determines whether an error is retried. Defaults to retrying all errors
+	ShouldRetry func(error) bool
+
+	// AroundCircuit retries calls around the circuit, so every attempt is tracked by the circuit and has its own
+	// timeout. The fallback is called once the attempts run out. Otherwise calls are retried inside the circuit, which
+	// tracks all attempts as one call bounded by its timeout
+	AroundCircuit bool
+}
+
+// CircuitWrapperPublisherHedged is a circuit wrapper for circuitgentest.Publisher
+type CircuitWrapperPublisherHedged struct {
+	circuitgentest.Publisher
+
+	// ShouldSkipError determines whether an error should be skipped and have the circuit
+	// track the call as successful. This takes precedence over IsBadRequest
+	ShouldSkipError func(error) bool
+
+	// IsBadRequest checks whether to count a user error against the circuit. It is recommended to set this
+	IsBadRequest func(error) bool
+
+	// RecoverPanics recovers panics of the embedded methods into a CircuitWrapperPublisherHedgedPanicError
+	RecoverPanics bool
+
+	// Repanic panics again with recovered panics after the circuit counted them
+	Repanic bool
+
+	// CanceledAsBadRequest counts calls failing after the caller's context is done as bad requests
+	CanceledAsBadRequest bool
+
+	// Bypass returns whether calls with the context bypass the circuits
+	Bypass func(context.Context) bool
+
+	// OnBypass is called with the method name of every bypassed call
+	OnBypass func(method string)
+
+	// CircuitPublish is the circuit for method Publish
+	CircuitPublish *circuit.Circuit
+	// FallbackPublish is the optional fallback for method Publish
+	FallbackPublish func(context.Context, map[circuitgentest.Seed][][]circuitgentest.Grant, circuitgentest.TopicsList, []rep.PublishOption, error) (map[string]struct{}, error)
+	// RetryPublish is the optional retry policy for method Publish
+	RetryPublish *CircuitWrapperPublisherHedgedRetryPolicy
+	// KeyPublish is the optional key function of the keyed circuits for method Publish
+	KeyPublish           func(context.Context, map[circuitgentest.Seed][][]circuitgentest.Grant, circuitgentest.TopicsList, []rep.PublishOption) string
+	keyedCircuitsPublish *circuitWrapperPublisherHedgedKeyedCircuits
+	// ShouldSkipErrorPublish determines whether an error of method Publish should be skipped
+	ShouldSkipErrorPublish func(context.Context, map[circuitgentest.Seed][][]circuitgentest.Grant, circuitgentest.TopicsList, []rep.PublishOption, error) bool
+	// IsBadRequestPublish checks whether to count an error of method Publish against the circuit
+	IsBadRequestPublish func(context.Context, map[circuitgentest.Seed][][]circuitgentest.Grant, circuitgentest.TopicsList, []rep.PublishOption, error) bool
+	// HedgePublish is the delay before hedging calls of method Publish. Calls aren't hedged if not positive
+	HedgePublish time.Duration
+	// CircuitPublishWithResult is the circuit for method PublishWithResult
+	CircuitPublishWithResult *circuit.Circuit
+	// FallbackPublishWithResult is the optional fallback for method PublishWithResult
+	FallbackPublishWithResult func(context.Context, rep.PublishInput, error) (*model.Result, error)
+	// RetryPublishWithResult is the optional retry policy for method PublishWithResult
+	RetryPublishWithResult *CircuitWrapperPublisherHedgedRetryPolicy
+	// KeyPublishWithResult is the optional key function of the keyed circuits for method PublishWithResult
+	KeyPublishWithResult           func(context.Context, rep.PublishInput) string
+	keyedCircuitsPublishWithResult *circuitWrapperPublisherHedgedKeyedCircuits
+	// ShouldSkipErrorPublishWithResult determines whether an error of method PublishWithResult should be skipped
+	ShouldSkipErrorPublishWithResult func(context.Context, rep.PublishInput, error) bool
+	// IsBadRequestPublishWithResult checks whether to count an error of method PublishWithResult against the circuit
+	IsBadRequestPublishWithResult func(context.Context, rep.PublishInput, error) bool
+	// HedgePublishWithResult is the delay before hedging calls of method PublishWithResult. Calls aren't hedged if not positive
+	HedgePublishWithResult time.Duration
+}
+
+// NewCircuitWrapperPublisherHedged creates a new circuit wrapper and initializes circuits
+func NewCircuitWrapperPublisherHedged(
+	manager *circuit.Manager,
+	embedded circuitgentest.Publisher,
+	conf CircuitWrapperPublisherHedgedConfig,
+) (*CircuitWrapperPublisherHedged, error) {
+	if conf.ShouldSkipError == nil {
+		conf.ShouldSkipError = func(err error) bool {
+			return false
+		}
+	}
+
+	if conf.IsBadRequest == nil {
+		conf.IsBadRequest = func(err error) bool {
+			return false
+		}
+	}
+
+	if conf.MaxKeys <= 0 {
+		conf.MaxKeys = 1000
+	}
+
+	w := &CircuitWrapperPublisherHedged{
+		Publisher:                      embedded,
+		ShouldSkipError:                conf.ShouldSkipError,
+		IsBadRequest:                   conf.IsBadRequest,
+		RecoverPanics:                  conf.RecoverPanics,
+		Repanic:                        conf.Repanic,
+		CanceledAsBadRequest:           conf.CanceledAsBadRequest,
+		Bypass:                         conf.Bypass,
+		OnBypass:                       conf.OnBypass,
+		FallbackPublish:                conf.FallbackPublish,
+		KeyPublish:                     conf.KeyPublish,
+		keyedCircuitsPublish:           newCircuitWrapperPublisherHedgedKeyedCircuits(manager, conf.Prefix+"PublisherHedged.Publish", conf.MaxKeys, conf.CircuitPublish, conf.Defaults),
+		HedgePublish:                   conf.HedgePublish,
+		FallbackPublishWithResult:      conf.FallbackPublishWithResult,
+		KeyPublishWithResult:           conf.KeyPublishWithResult,
+		keyedCircuitsPublishWithResult: newCircuitWrapperPublisherHedgedKeyedCircuits(manager, conf.Prefix+"PublisherHedged.PublishWithResult", conf.MaxKeys, conf.CircuitPublishWithResult, conf.Defaults),
+		HedgePublishWithResult:         conf.HedgePublishWithResult,
+	}
+
+	var err error
+
+	w.CircuitPublish, err = manager.CreateCircuit(conf.Prefix+"PublisherHedged.Publish", conf.CircuitPublish, conf.Defaults)
+	if err != nil {
+		return nil, err
+	}
+
+	w.RetryPublish = conf.RetryPublish
+	if w.RetryPublish == nil {
+		w.RetryPublish = conf.Retry
+	}
+
+	w.ShouldSkipErrorPublish = conf.ShouldSkipErrorPublish
+	if w.ShouldSkipErrorPublish == nil {
+		w.ShouldSkipErrorPublish = func(_ context.Context, _ map[circuitgentest.Seed][][]circuitgentest.Grant, _ circuitgentest.TopicsList, _ []rep.PublishOption, err error) bool {
+			return w.ShouldSkipError(err)
+		}
+	}
+
+	w.IsBadRequestPublish = conf.IsBadRequestPublish
+	if w.IsBadRequestPublish == nil {
+		w.IsBadRequestPublish = func(_ context.Context, _ map[circuitgentest.Seed][][]circuitgentest.Grant, _ circuitgentest.TopicsList, _ []rep.PublishOption, err error) bool {
+			return w.IsBadRequest(err)
+		}
+	}
+
+	w.CircuitPublishWithResult, err = manager.CreateCircuit(conf.Prefix+"PublisherHedged.PublishWithResult", conf.CircuitPublishWithResult, conf.Defaults)
+	if err != nil {
+		return nil, err
+	}
+
+	w.RetryPublishWithResult = conf.RetryPublishWithResult
+	if w.RetryPublishWithResult == nil {
+		w.RetryPublishWithResult = conf.Retry
+	}
+
+	w.ShouldSkipErrorPublishWithResult = conf.ShouldSkipErrorPublishWithResult
+	if w.ShouldSkipErrorPublishWithResult == nil {
+		w.ShouldSkipErrorPublishWithResult = func(_ context.Context, _ rep.PublishInput, err error) bool {
+			return w.ShouldSkipError(err)
+		}
+	}
+
+	w.IsBadRequestPublishWithResult = conf.IsBadRequestPublishWithResult
+	if w.IsBadRequestPublishWithResult == nil {
+		w.IsBadRequestPublishWithResult = func(_ context.Context, _ rep.PublishInput, err error) bool {
+			return w.IsBadRequest(err)
+		}
+	}
+
+	return w, nil
+}
+
+// Publish calls the embedded circuitgentest.Publisher's method Publish with CircuitPublish. Calls are
+// hedged after HedgePublish
+func (w *CircuitWrapperPublisherHedged) Publish(ctx context.Context, p1 map[circuitgentest.Seed][][]circuitgentest.Grant, p2 circuitgentest.TopicsList, p3 ...rep.PublishOption) (map[string]struct{}, error) {
+	callerCtx := ctx
+	if w.bypassed(callerCtx, "Publish") {
+		return w.Publisher.Publish(ctx, p1, p2, p3...)
+	}
+
+	c := w.CircuitPublish
+	if w.KeyPublish != nil {
+		var err error
+		if c, err = w.keyedCircuitsPublish.get(w.KeyPublish(ctx, p1, p2, p3), c); err != nil {
+			var r0 map[string]struct{}
+			return r0, err
+		}
+	}
+
+	var r0 [2]map[string]struct{}
+	var skippedErr [2]error
+
+	n, err := w.hedge(callerCtx, w.HedgePublish, func(ctx context.Context, i int) error {
+		var fallback func(context.Context, error) error
+		if w.FallbackPublish != nil {
+			fallback = func(ctx context.Context, err error) error {
+				r0[i], err = w.FallbackPublish(ctx, p1, p2, p3, err)
+				return err
+			}
+		}
+
+		return w.RetryPublish.run(ctx, c, func(ctx context.Context) (err error) {
+			defer w.recoverPanic("Publish", &err)
+
+			r0[i], err = w.Publisher.Publish(ctx, p1, p2, p3...)
+
+			if w.isCanceledBadRequest(callerCtx, err) {
+				return &circuit.SimpleBadRequest{Err: err}
+			}
+
+			if w.ShouldSkipErrorPublish(ctx, p1, p2, p3, err) {
+				skippedErr[i] = err
+				return nil
+			}
+
+			if w.IsBadRequestPublish(ctx, p1, p2, p3, err) {
+				return &circuit.SimpleBadRequest{Err: err}
+			}
+			return err
+		}, fallback)
+	})
+	err = w.circuitError(callerCtx, c, "Publish", err)
+	w.repanic(err)
+
+	if skippedErr[n] != nil {
+		err = skippedErr[n]
+	}
+
+	// The circuit or its hooks may wrap the bad request
+	var berr *circuit.SimpleBadRequest
+	if errors.As(err, &berr) {
+		err = berr.Err
+	}
+
+	return r0[n], err
+}
+
+// PublishWithResult calls the embedded circuitgentest.Publisher's method PublishWithResult with CircuitPublishWithResult. Calls are
+// hedged after HedgePublishWithResult
+func (w *CircuitWrapperPublisherHedged) PublishWithResult(ctx context.Context, p1 rep.PublishInput) (*model.Result, error) {
+	callerCtx := ctx
+	if w.bypassed(callerCtx, "PublishWithResult") {
+		return w.Publisher.PublishWithResult(ctx, p1)
+	}
+
+	c := w.CircuitPublishWithResult
+	if w.KeyPublishWithResult != nil {
+		var err error
+		if c, err = w.keyedCircuitsPublishWithResult.get(w.KeyPublishWithResult(ctx, p1), c); err != nil {
+			var r0 *model.Result
+			return r0, err
+		}
+	}
+
+	var r0 [2]*model.Result
+	var skippedErr [2]error
+
+	n, err := w.hedge(callerCtx, w.HedgePublishWithResult, func(ctx context.Context, i int) error {
+		var fallback func(context.Context, error) error
+		if w.FallbackPublishWithResult != nil {
+			fallback = func(ctx context.Context, err error) error {
+				r0[i], err = w.FallbackPublishWithResult(ctx, p1, err)
+				return err
+			}
+		}
+
+		return w.RetryPublishWithResult.run(ctx, c, func(ctx context.Context) (err error) {
+			defer w.recoverPanic("PublishWithResult", &err)
+
+			r0[i], err = w.Publisher.PublishWithResult(ctx, p1)
+
+			if w.isCanceledBadRequest(callerCtx, err) {
+				return &circuit.SimpleBadRequest{Err: err}
+			}
+
+			if w.ShouldSkipErrorPublishWithResult(ctx, p1, err) {
+				skippedErr[i] = err
+				return nil
+			}
+
+			if w.IsBadRequestPublishWithResult(ctx, p1, err) {
+				return &circuit.SimpleBadRequest{Err: err}
+			}
+			return err
+		}, fallback)
+	})
+	err = w.circuitError(callerCtx, c, "PublishWithResult", err)
+	w.repanic(err)
+
+	if skippedErr[n] != nil {
+		err = skippedErr[n]
+	}
+
+	// The circuit or its hooks may wrap the bad request
+	var berr *circuit.SimpleBadRequest
+	if errors.As(err, &berr) {
+		err = berr.Err
+	}
+
+	return r0[n], err
+}
+
+// hedge calls attempt with the index 0, and again with the index 1 if the first call hasn't finished after the delay.
+// It returns the index and error of the first attempt to succeed, or of the last to fail. The context of an attempt
+// still running is cancelled. A panic of an attempt is raised again in the calling goroutine. Calls aren't hedged if
+// the delay isn't positive
+func (w *CircuitWrapperPublisherHedged) hedge(ctx context.Context, delay time.Duration, attempt func(context.Context, int) error) (int, error) {
+	if delay <= 0 {
+		return 0, attempt(ctx, 0)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		i         int
+		err       error
+		recovered interface{}
+	}
+	// Buffered so the cancelled attempt doesn't block
+	results := make(chan result, 2)
+	call := func(i int) {
+		// Attempts run in their own goroutines, where a panic would crash the program instead of reaching the caller
+		defer func() {
+			if r := recover(); r != nil {
+				results <- result{i: i, recovered: r}
+			}
+		}()
+		results <- result{i: i, err: attempt(ctx, i)}
+	}
+	go call(0)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	started, finished := 1, 0
+	for {
+		select {
+		case <-timer.C:
+			started++
+			go call(1)
+		case r := <-results:
+			if r.recovered != nil {
+				panic(r.recovered)
+			}
+			finished++
+			if r.err == nil || finished == started {
+				return r.i, r.err
+			}
+		}
+	}
+}
+
+// CircuitWrapperPublisherHedgedError is returned by CircuitWrapperPublisherHedged when a circuit rejects a call because it is
+// open or at its concurrency limit, or when a call times out. Other errors of the embedded methods are returned as is.
+// It implements the circuit.Error interface of cep21/circuit v3
+type CircuitWrapperPublisherHedgedError struct {
+	// Circuit is the name of the circuit
+	Circuit string
+
+	// Method is the name of the wrapped method
+	Method string
+
+	// Err is the error of the circuit, or of the method if it timed out
+	Err error
+
+	circuitOpen             bool
+	concurrencyLimitReached bool
+	timeout                 bool
+}
+
+func (e *CircuitWrapperPublisherHedgedError) Error() string {
+	return e.Circuit + ": " + e.Err.Error()
+}
+
+// Unwrap returns the cause
+func (e *CircuitWrapperPublisherHedgedError) Unwrap() error {
+	return e.Err
+}
+
+// CircuitOpen returns true if the call was rejected because the circuit is open
+func (e *CircuitWrapperPublisherHedgedError) CircuitOpen() bool {
+	return e.circuitOpen
+}
+
+// ConcurrencyLimitReached returns true if the call was rejected because the circuit reached its concurrency limit
+func (e *CircuitWrapperPublisherHedgedError) ConcurrencyLimitReached() bool {
+	return e.concurrencyLimitReached
+}
+
+// Timeout returns true if the call exceeded the circuit's timeout
+func (e *CircuitWrapperPublisherHedgedError) Timeout() bool {
+	return e.timeout
+}
+
+// circuitWrapperPublisherHedgedKeyedCircuits lazily creates the keyed circuits of a method of CircuitWrapperPublisherHedged on the manager.
+// The manager can't remove circuits, so calls with new keys use the circuit of the method once there are maxKeys
+type circuitWrapperPublisherHedgedKeyedCircuits struct {
+	manager *circuit.Manager
+	name    string
+	maxKeys int
+	configs []circuit.Config
+
+	mu       sync.Mutex
+	circuits map[string]*circuit.Circuit
+}
+
+func newCircuitWrapperPublisherHedgedKeyedCircuits(manager *circuit.Manager, name string, maxKeys int, configs ...circuit.Config) *circuitWrapperPublisherHedgedKeyedCircuits {
+	return &circuitWrapperPublisherHedgedKeyedCircuits{
+		manager:  manager,
+		name:     name,
+		maxKeys:  maxKeys,
+		configs:  configs,
+		circuits: make(map[string]*circuit.Circuit),
+	}
+}
+
+// get returns the circuit of the key, creating it if needed. The default circuit is returned for an empty key or once
+// there are maxKeys circuits
+func (k *circuitWrapperPublisherHedgedKeyedCircuits) get(key string, defaultCircuit *circuit.Circuit) (*circuit.Circuit, error) {
+	if key == "" {
+		return defaultCircuit, nil
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if c, ok := k.circuits[key]; ok {
+		return c, nil
+	}
+	if len(k.circuits) >= k.maxKeys {
+		return defaultCircuit, nil
+	}
+
+	name := k.name + "." + key
+	c, err := k.manager.CreateCircuit(name, k.configs...)
+	if err != nil {
+		return nil, fmt.Errorf("creating keyed circuit %s: %w", name, err)
+	}
+	k.circuits[key] = c
+	return c, nil
+}
+
+// CircuitWrapperPublisherHedgedPanicError is returned by CircuitWrapperPublisherHedged when an embedded method panics and
+// RecoverPanics is set
+type CircuitWrapperPublisherHedgedPanicError struct {
+	// Method is the name of the wrapped method
+	Method string
+
+	// Value is the value the method panicked with
+	Value interface{}
+
+	// Stack is the stack trace of the panicking goroutine
+	Stack []byte
+}
+
+func (e *CircuitWrapperPublisherHedgedPanicError) Error() string {
+	return "panic in " + e.Method + ": " + fmt.Sprint(e.Value)
+}
+
+// Unwrap returns the value the method panicked with if it is an error
+func (e *CircuitWrapperPublisherHedgedPanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// recoverPanic recovers a panic of the embedded method into the error of the call if RecoverPanics is set. It must be
+// deferred by the call
+func (w *CircuitWrapperPublisherHedged) recoverPanic(method string, err *error) {
+	if !w.RecoverPanics {
+		return
+	}
+
+	if r := recover(); r != nil {
+		*err = &CircuitWrapperPublisherHedgedPanicError{Method: method, Value: r, Stack: debug.Stack()}
+	}
+}
+
+// repanic panics with the CircuitWrapperPublisherHedgedPanicError of a call if Repanic is set
+func (w *CircuitWrapperPublisherHedged) repanic(err error) {
+	var perr *CircuitWrapperPublisherHedgedPanicError
+	if w.Repanic && errors.As(err, &perr) {
+		panic(perr)
+	}
+}
+
+// bypassed returns whether a call bypasses the circuits, and reports it to OnBypass
+func (w *CircuitWrapperPublisherHedged) bypassed(ctx context.Context, method string) bool {
+	if w.Bypass == nil || !w.Bypass(ctx) {
+		return false
+	}
+
+	if w.OnBypass != nil {
+		w.OnBypass(method)
+	}
+	return true
+}
+
+// isCanceledBadRequest returns whether the error of a call is counted as a bad request because the caller's context is
+// done. The circuit's timeout doesn't end the caller's context
+func (w *CircuitWrapperPublisherHedged) isCanceledBadRequest(callerCtx context.Context, err error) bool {
+	return w.CanceledAsBadRequest && err != nil && callerCtx.Err() != nil
+}
+
+// circuitError wraps the error of a call in a CircuitWrapperPublisherHedgedError if the circuit rejected the call, or if it
+// failed with context.DeadlineExceeded while the caller's context isn't done
+func (w *CircuitWrapperPublisherHedged) circuitError(ctx context.Context, c *circuit.Circuit, method string, err error) error {
+	var berr *circuit.SimpleBadRequest
+	if err == nil || errors.As(err, &berr) {
+		return err
+	}
+
+	if cerr, ok := err.(interface {
+		CircuitOpen() bool
+		ConcurrencyLimitReached() bool
+	}); ok {
+		return &CircuitWrapperPublisherHedgedError{
+			Circuit:                 c.Name(),
+			Method:                  method,
+			Err:                     err,
+			circuitOpen:             cerr.CircuitOpen(),
+			concurrencyLimitReached: cerr.ConcurrencyLimitReached(),
+		}
+	}
+
+	if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+		return &CircuitWrapperPublisherHedgedError{Circuit: c.Name(), Method: method, Err: err, timeout: true}
+	}
+
+	return err
+}
+
+// run executes the circuit with the policy. Calls are attempted once if the policy is nil
+func (p *CircuitWrapperPublisherHedgedRetryPolicy) run(ctx context.Context, c *circuit.Circuit, runFunc func(context.Context) error, fallbackFunc func(context.Context, error) error) error {
+	if p == nil || p.MaxAttempts < 2 {
+		return c.Execute(ctx, runFunc, fallbackFunc)
+	}
+
+	if p.AroundCircuit {
+		// Falling back on every attempt would stop the retries, so the fallback only gets the error of the last one
+		err := p.retry(ctx, func(ctx context.Context) error {
+			return c.Execute(ctx, runFunc, nil)
+		})
+		if err != nil && fallbackFunc != nil && !circuit.IsBadRequest(err) {
+			return fallbackFunc(ctx, err)
+		}
+		return err
+	}
+
+	return c.Execute(ctx, func(ctx context.Context) error {
+		return p.retry(ctx, runFunc)
+	}, fallbackFunc)
+}
+
+// retry calls f until it succeeds, its error isn't retried, the attempts run out, or the context would be done
+// before the next attempt
+func (p *CircuitWrapperPublisherHedgedRetryPolicy) retry(ctx context.Context, f func(context.Context) error) error {
+	backoff := p.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := f(ctx)
+		if err == nil || attempt >= p.MaxAttempts || !p.isRetried(err) {
+			return err
+		}
+
+		wait := backoff
+		if p.Jitter > 0 {
+			wait -= time.Duration(p.Jitter * rand.Float64() * float64(backoff)) // #nosec G404 jitter doesn't need a secure random
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+	}
+}
+
+// isRetried returns whether the error of an attempt is retried
+func (p *CircuitWrapperPublisherHedgedRetryPolicy) isRetried(err error) bool {
+	var berr *circuit.SimpleBadRequest
+	if errors.As(err, &berr) {
+		return false
+	}
+	if cerr, ok := err.(interface{ CircuitOpen() bool }); ok && cerr.CircuitOpen() {
+		return false
+	}
+	var perr *CircuitWrapperPublisherHedgedPanicError
+	if errors.As(err, &perr) {
+		return false
+	}
+	return p.ShouldRetry == nil || p.ShouldRetry(err)
+}
+
+var _ circuitgentest.Publisher = (*CircuitWrapperPublisherHedged)(nil)
@@ -4,6 +4,12 @@ package circuittest
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"runtime/debug"
+	"sync"
+	"time"
 
 	"github.com/cep21/circuit"
 	"github.com/twitchtv/circuitgen/internal/circuitgentest"
@@ -18,6 +24,9 @@ type CircuitWrapperPubsubConfig struct {
 	ShouldSkipError func(error) bool
 
 	// IsBadRequest is an optional bad request checker. It is useful to not count user errors as faults
+	//
+	// Both checkers receive the error of the embedded method as is, so wrapped errors can be checked with errors.Is
+	// and errors.As
 	IsBadRequest func(error) bool
 
 	// Prefix is prepended to all circuit names
@@ -26,10 +35,88 @@ type CircuitWrapperPubsubConfig struct {
 	// Defaults are used for all created circuits. Per-circuit configs override this
 	Defaults circuit.Config
 
+	// MaxKeys bounds the number of keyed circuits of each method. Calls with new keys use the circuit of the method once
+	// it is reached. Defaults to 1000
+	MaxKeys int
+
+	// Retry is the retry policy of all methods. Calls are attempted once if nil
+	Retry *CircuitWrapperPubsubRetryPolicy
+
+	// RecoverPanics recovers panics of the embedded methods into a CircuitWrapperPubsubPanicError, which the
+	// circuit counts as a failure. Panics are not recovered by default
+	RecoverPanics bool
+
+	// Repanic panics again with the CircuitWrapperPubsubPanicError of a recovered panic after the circuit counted
+	// it, unless a fallback handled it. This has no effect without RecoverPanics
+	Repanic bool
+
+	// CanceledAsBadRequest counts calls failing after the caller's context is done as bad requests, which don't fall
+	// back and aren't retried. Otherwise the circuit counts them as interrupts, unless it ignores interrupts. Either
+	// way they don't count as failures, unlike the circuit's timeouts
+	CanceledAsBadRequest bool
+
+	// Bypass returns whether calls with the context bypass the circuits and call the embedded methods directly, ex.
+	// for admin tools. Bypassed calls aren't tracked by the circuits. Set it to circuitbypass.IsBypassed to bypass
+	// calls with contexts from circuitbypass.WithCircuitBypass. Calls aren't bypassed if nil
+	Bypass func(context.Context) bool
+
+	// OnBypass is called with the method name of every bypassed call if set, ex. to report metrics
+	OnBypass func(method string)
+
 	// CircuitPublish is the configuration used for the Publish circuit. This overrides values set by Defaults
 	CircuitPublish circuit.Config
+	// FallbackPublish is called with the params of Publish and the circuit error when the call fails or
+	// the circuit is open. Its results are returned instead. Bad requests and skipped errors don't fall back
+	FallbackPublish func(context.Context, map[circuitgentest.Seed][][]circuitgentest.Grant, circuitgentest.TopicsList, []rep.PublishOption, error) (map[string]struct{}, error)
+	// RetryPublish is the retry policy of Publish. This overrides Retry
+	RetryPublish *CircuitWrapperPubsubRetryPolicy
+	// KeyPublish returns the key of a call of Publish. Calls with a non-empty key use a circuit of the key
+	// named Prefix+"Pubsub.Publish.<key>", created on first use with CircuitPublish. Otherwise calls use
+	// CircuitPublish
+	KeyPublish func(context.Context, map[circuitgentest.Seed][][]circuitgentest.Grant, circuitgentest.TopicsList, []rep.PublishOption) string
+	// ShouldSkipErrorPublish overrides ShouldSkipError for Publish. It receives the params of the call
+	ShouldSkipErrorPublish func(context.Context, map[circuitgentest.Seed][][]circuitgentest.Grant, circuitgentest.TopicsList, []rep.PublishOption, error) bool
+	// IsBadRequestPublish overrides IsBadRequest for Publish. It receives the params of the call
+	IsBadRequestPublish func(context.Context, map[circuitgentest.Seed][][]circuitgentest.Grant, circuitgentest.TopicsList, []rep.PublishOption, error) bool
 	// CircuitPublishWithResult is the configuration used for the PublishWithResult circuit. This overrides values set by Defaults
 	CircuitPublishWithResult circuit.Config
+	// FallbackPublishWithResult is called with the params of PublishWithResult and the circuit error when the call fails or
+	// the circuit is open. Its results are returned instead. Bad requests and skipped errors don't fall back
+	FallbackPublishWithResult func(context.Context, rep.PublishInput, error) (*model.Result, error)
+	// RetryPublishWithResult is the retry policy of PublishWithResult. This overrides Retry
+	RetryPublishWithResult *CircuitWrapperPubsubRetryPolicy
+	// KeyPublishWithResult returns the key of a call of PublishWithResult. Calls with a non-empty key use a circuit of the key
+	// named Prefix+"Pubsub.PublishWithResult.<key>", created on first use with CircuitPublishWithResult. Otherwise calls use
+	// CircuitPublishWithResult
+	KeyPublishWithResult func(context.Context, rep.PublishInput) string
+	// ShouldSkipErrorPublishWithResult overrides ShouldSkipError for PublishWithResult. It receives the params of the call
+	ShouldSkipErrorPublishWithResult func(context.Context, rep.PublishInput, error) bool
+	// IsBadRequestPublishWithResult overrides IsBadRequest for PublishWithResult. It receives the params of the call
+	IsBadRequestPublishWithResult func(context.Context, rep.PublishInput, error) bool
+}
+
+// CircuitWrapperPubsubRetryPolicy configures retrying failed calls of CircuitWrapperPubsub. Bad requests,
+// skipped errors, open circuits, and recovered panics are never retried
+type CircuitWrapperPubsubRetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts of a call, including the first. Calls are attempted once if less than 2
+	MaxAttempts int
+
+	// InitialBackoff is the backoff before the first retry. It doubles for every retry after
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the backoff between retries if set
+	MaxBackoff time.Duration
+
+	// Jitter is the fraction of each backoff that is randomly subtracted from it, between 0 and 1. 1 is full jitter
+	Jitter float64
+
+	// ShouldRetry determines whether an error is retried. Defaults to retrying all errors
+	ShouldRetry func(error) bool
+
+	// AroundCircuit retries calls around the circuit, so every attempt is tracked by the circuit and has its own
+	// timeout. The fallback is called once the attempts run out. Otherwise calls are retried inside the circuit, which
+	// tracks all attempts as one call bounded by its timeout
+	AroundCircuit bool
 }
 
 // CircuitWrapperPubsub is a circuit wrapper for circuitgentest.Publisher
@@ -43,10 +130,47 @@ type CircuitWrapperPubsub struct {
 	// IsBadRequest checks whether to count a user error against the circuit. It is recommended to set this
 	IsBadRequest func(error) bool
 
+	// RecoverPanics recovers panics of the embedded methods into a CircuitWrapperPubsubPanicError
+	RecoverPanics bool
+
+	// Repanic panics again with recovered panics after the circuit counted them
+	Repanic bool
+
+	// CanceledAsBadRequest counts calls failing after the caller's context is done as bad requests
+	CanceledAsBadRequest bool
+
+	// Bypass returns whether calls with the context bypass the circuits
+	Bypass func(context.Context) bool
+
+	// OnBypass is called with the method name of every bypassed call
+	OnBypass func(method string)
+
 	// CircuitPublish is the circuit for method Publish
 	CircuitPublish *circuit.Circuit
+	// FallbackPublish is the optional fallback for method Publish
+	FallbackPublish func(context.Context, map[circuitgentest.Seed][][]circuitgentest.Grant, circuitgentest.TopicsList, []rep.PublishOption, error) (map[string]struct{}, error)
+	// RetryPublish is the optional retry policy for method Publish
+	RetryPublish *CircuitWrapperPubsubRetryPolicy
+	// KeyPublish is the optional key function of the keyed circuits for method Publish
+	KeyPublish           func(context.Context, map[circuitgentest.Seed][][]circuitgentest.Grant, circuitgentest.TopicsList, []rep.PublishOption) string
+	keyedCircuitsPublish *circuitWrapperPubsubKeyedCircuits
+	// ShouldSkipErrorPublish determines whether an error of method Publish should be skipped
+	ShouldSkipErrorPublish func(context.Context, map[circuitgentest.Seed][][]circuitgentest.Grant, circuitgentest.TopicsList, []rep.PublishOption, error) bool
+	// IsBadRequestPublish checks whether to count an error of method Publish against the circuit
+	IsBadRequestPublish func(context.Context, map[circuitgentest.Seed][][]circuitgentest.Grant, circuitgentest.TopicsList, []rep.PublishOption, error) bool
 	// CircuitPublishWithResult is the circuit for method PublishWithResult
 	CircuitPublishWithResult *circuit.Circuit
+	// FallbackPublishWithResult is the optional fallback for method PublishWithResult
+	FallbackPublishWithResult func(context.Context, rep.PublishInput, error) (*model.Result, error)
+	// RetryPublishWithResult is the optional retry policy for method PublishWithResult
+	RetryPublishWithResult *CircuitWrapperPubsubRetryPolicy
+	// KeyPublishWithResult is the optional key function of the keyed circuits for method PublishWithResult
+	KeyPublishWithResult           func(context.Context, rep.PublishInput) string
+	keyedCircuitsPublishWithResult *circuitWrapperPubsubKeyedCircuits
+	// ShouldSkipErrorPublishWithResult determines whether an error of method PublishWithResult should be skipped
+	ShouldSkipErrorPublishWithResult func(context.Context, rep.PublishInput, error) bool
+	// IsBadRequestPublishWithResult checks whether to count an error of method PublishWithResult against the circuit
+	IsBadRequestPublishWithResult func(context.Context, rep.PublishInput, error) bool
 }
 
 // NewCircuitWrapperPubsub creates a new circuit wrapper and initializes circuits
@@ -67,10 +191,25 @@ func NewCircuitWrapperPubsub(
 		}
 	}
 
+	if conf.MaxKeys <= 0 {
+		conf.MaxKeys = 1000
+	}
+
 	w := &CircuitWrapperPubsub{
-		Publisher:       embedded,
-		ShouldSkipError: conf.ShouldSkipError,
-		IsBadRequest:    conf.IsBadRequest,
+		Publisher:                      embedded,
+		ShouldSkipError:                conf.ShouldSkipError,
+		IsBadRequest:                   conf.IsBadRequest,
+		RecoverPanics:                  conf.RecoverPanics,
+		Repanic:                        conf.Repanic,
+		CanceledAsBadRequest:           conf.CanceledAsBadRequest,
+		Bypass:                         conf.Bypass,
+		OnBypass:                       conf.OnBypass,
+		FallbackPublish:                conf.FallbackPublish,
+		KeyPublish:                     conf.KeyPublish,
+		keyedCircuitsPublish:           newCircuitWrapperPubsubKeyedCircuits(manager, conf.Prefix+"Pubsub.Publish", conf.MaxKeys, conf.CircuitPublish, conf.Defaults),
+		FallbackPublishWithResult:      conf.FallbackPublishWithResult,
+		KeyPublishWithResult:           conf.KeyPublishWithResult,
+		keyedCircuitsPublishWithResult: newCircuitWrapperPubsubKeyedCircuits(manager, conf.Prefix+"Pubsub.PublishWithResult", conf.MaxKeys, conf.CircuitPublishWithResult, conf.Defaults),
 	}
 
 	var err error
@@ -80,39 +219,108 @@ func NewCircuitWrapperPubsub(
 		return nil, err
 	}
 
+	w.RetryPublish = conf.RetryPublish
+	if w.RetryPublish == nil {
+		w.RetryPublish = conf.Retry
+	}
+
+	w.ShouldSkipErrorPublish = conf.ShouldSkipErrorPublish
+	if w.ShouldSkipErrorPublish == nil {
+		w.ShouldSkipErrorPublish = func(_ context.Context, _ map[circuitgentest.Seed][][]circuitgentest.Grant, _ circuitgentest.TopicsList, _ []rep.PublishOption, err error) bool {
+			return w.ShouldSkipError(err)
+		}
+	}
+
+	w.IsBadRequestPublish = conf.IsBadRequestPublish
+	if w.IsBadRequestPublish == nil {
+		w.IsBadRequestPublish = func(_ context.Context, _ map[circuitgentest.Seed][][]circuitgentest.Grant, _ circuitgentest.TopicsList, _ []rep.PublishOption, err error) bool {
+			return w.IsBadRequest(err)
+		}
+	}
+
 	w.CircuitPublishWithResult, err = manager.CreateCircuit(conf.Prefix+"Pubsub.PublishWithResult", conf.CircuitPublishWithResult, conf.Defaults)
 	if err != nil {
 		return nil, err
 	}
 
+	w.RetryPublishWithResult = conf.RetryPublishWithResult
+	if w.RetryPublishWithResult == nil {
+		w.RetryPublishWithResult = conf.Retry
+	}
+
+	w.ShouldSkipErrorPublishWithResult = conf.ShouldSkipErrorPublishWithResult
+	if w.ShouldSkipErrorPublishWithResult == nil {
+		w.ShouldSkipErrorPublishWithResult = func(_ context.Context, _ rep.PublishInput, err error) bool {
+			return w.ShouldSkipError(err)
+		}
+	}
+
+	w.IsBadRequestPublishWithResult = conf.IsBadRequestPublishWithResult
+	if w.IsBadRequestPublishWithResult == nil {
+		w.IsBadRequestPublishWithResult = func(_ context.Context, _ rep.PublishInput, err error) bool {
+			return w.IsBadRequest(err)
+		}
+	}
+
 	return w, nil
 }
 
 // Publish calls the embedded circuitgentest.Publisher's method Publish with CircuitPublish
 func (w *CircuitWrapperPubsub) Publish(ctx context.Context, p1 map[circuitgentest.Seed][][]circuitgentest.Grant, p2 circuitgentest.TopicsList, p3 ...rep.PublishOption) (map[string]struct{}, error) {
+	callerCtx := ctx
+	if w.bypassed(callerCtx, "Publish") {
+		return w.Publisher.Publish(ctx, p1, p2, p3...)
+	}
+
+	c := w.CircuitPublish
+	if w.KeyPublish != nil {
+		var err error
+		if c, err = w.keyedCircuitsPublish.get(w.KeyPublish(ctx, p1, p2, p3), c); err != nil {
+			var r0 map[string]struct{}
+			return r0, err
+		}
+	}
+
 	var r0 map[string]struct{}
 	var skippedErr error
 
-	err := w.CircuitPublish.Run(ctx, func(ctx context.Context) error {
-		var err error
+	var fallback func(context.Context, error) error
+	if w.FallbackPublish != nil {
+		fallback = func(ctx context.Context, err error) error {
+			r0, err = w.FallbackPublish(ctx, p1, p2, p3, err)
+			return err
+		}
+	}
+
+	err := w.RetryPublish.run(callerCtx, c, func(ctx context.Context) (err error) {
+		defer w.recoverPanic("Publish", &err)
+
 		r0, err = w.Publisher.Publish(ctx, p1, p2, p3...)
 
-		if w.ShouldSkipError(err) {
+		if w.isCanceledBadRequest(callerCtx, err) {
+			return &circuit.SimpleBadRequest{Err: err}
+		}
+
+		if w.ShouldSkipErrorPublish(ctx, p1, p2, p3, err) {
 			skippedErr = err
 			return nil
 		}
 
-		if w.IsBadRequest(err) {
+		if w.IsBadRequestPublish(ctx, p1, p2, p3, err) {
 			return &circuit.SimpleBadRequest{Err: err}
 		}
 		return err
-	})
+	}, fallback)
+	err = w.circuitError(callerCtx, c, "Publish", err)
+	w.repanic(err)
 
 	if skippedErr != nil {
 		err = skippedErr
 	}
 
-	if berr, ok := err.(*circuit.SimpleBadRequest); ok {
+	// The circuit or its hooks may wrap the bad request
+	var berr *circuit.SimpleBadRequest
+	if errors.As(err, &berr) {
 		err = berr.Err
 	}
 
@@ -121,33 +329,316 @@ func (w *CircuitWrapperPubsub) Publish(ctx context.Context, p1 map[circuitgentes
 
 // PublishWithResult calls the embedded circuitgentest.Publisher's method PublishWithResult with CircuitPublishWithResult
 func (w *CircuitWrapperPubsub) PublishWithResult(ctx context.Context, p1 rep.PublishInput) (*model.Result, error) {
+	callerCtx := ctx
+	if w.bypassed(callerCtx, "PublishWithResult") {
+		return w.Publisher.PublishWithResult(ctx, p1)
+	}
+
+	c := w.CircuitPublishWithResult
+	if w.KeyPublishWithResult != nil {
+		var err error
+		if c, err = w.keyedCircuitsPublishWithResult.get(w.KeyPublishWithResult(ctx, p1), c); err != nil {
+			var r0 *model.Result
+			return r0, err
+		}
+	}
+
 	var r0 *model.Result
 	var skippedErr error
 
-	err := w.CircuitPublishWithResult.Run(ctx, func(ctx context.Context) error {
-		var err error
+	var fallback func(context.Context, error) error
+	if w.FallbackPublishWithResult != nil {
+		fallback = func(ctx context.Context, err error) error {
+			r0, err = w.FallbackPublishWithResult(ctx, p1, err)
+			return err
+		}
+	}
+
+	err := w.RetryPublishWithResult.run(callerCtx, c, func(ctx context.Context) (err error) {
+		defer w.recoverPanic("PublishWithResult", &err)
+
 		r0, err = w.Publisher.PublishWithResult(ctx, p1)
 
-		if w.ShouldSkipError(err) {
+		if w.isCanceledBadRequest(callerCtx, err) {
+			return &circuit.SimpleBadRequest{Err: err}
+		}
+
+		if w.ShouldSkipErrorPublishWithResult(ctx, p1, err) {
 			skippedErr = err
 			return nil
 		}
 
-		if w.IsBadRequest(err) {
+		if w.IsBadRequestPublishWithResult(ctx, p1, err) {
 			return &circuit.SimpleBadRequest{Err: err}
 		}
 		return err
-	})
+	}, fallback)
+	err = w.circuitError(callerCtx, c, "PublishWithResult", err)
+	w.repanic(err)
 
 	if skippedErr != nil {
 		err = skippedErr
 	}
 
-	if berr, ok := err.(*circuit.SimpleBadRequest); ok {
+	// The circuit or its hooks may wrap the bad request
+	var berr *circuit.SimpleBadRequest
+	if errors.As(err, &berr) {
 		err = berr.Err
 	}
 
 	return r0, err
 }
 
+// CircuitWrapperPubsubError is returned by CircuitWrapperPubsub when a circuit rejects a call because it is
+// open or at its concurrency limit, or when a call times out. Other errors of the embedded methods are returned as is.
+// It implements the circuit.Error interface of cep21/circuit v3
+type CircuitWrapperPubsubError struct {
+	// Circuit is the name of the circuit
+	Circuit string
+
+	// Method is the name of the wrapped method
+	Method string
+
+	// Err is the error of the circuit, or of the method if it timed out
+	Err error
+
+	circuitOpen             bool
+	concurrencyLimitReached bool
+	timeout                 bool
+}
+
+func (e *CircuitWrapperPubsubError) Error() string {
+	return e.Circuit + ": " + e.Err.Error()
+}
+
+// Unwrap returns the cause
+func (e *CircuitWrapperPubsubError) Unwrap() error {
+	return e.Err
+}
+
+// CircuitOpen returns true if the call was rejected because the circuit is open
+func (e *CircuitWrapperPubsubError) CircuitOpen() bool {
+	return e.circuitOpen
+}
+
+// ConcurrencyLimitReached returns true if the call was rejected because the circuit reached its concurrency limit
+func (e *CircuitWrapperPubsubError) ConcurrencyLimitReached() bool {
+	return e.concurrencyLimitReached
+}
+
+// Timeout returns true if the call exceeded the circuit's timeout
+func (e *CircuitWrapperPubsubError) Timeout() bool {
+	return e.timeout
+}
+
+// circuitWrapperPubsubKeyedCircuits lazily creates the keyed circuits of a method of CircuitWrapperPubsub on the manager.
+// The manager can't remove circuits, so calls with new keys use the circuit of the method once there are maxKeys
+type circuitWrapperPubsubKeyedCircuits struct {
+	manager *circuit.Manager
+	name    string
+	maxKeys int
+	configs []circuit.Config
+
+	mu       sync.Mutex
+	circuits map[string]*circuit.Circuit
+}
+
+func newCircuitWrapperPubsubKeyedCircuits(manager *circuit.Manager, name string, maxKeys int, configs ...circuit.Config) *circuitWrapperPubsubKeyedCircuits {
+	return &circuitWrapperPubsubKeyedCircuits{
+		manager:  manager,
+		name:     name,
+		maxKeys:  maxKeys,
+		configs:  configs,
+		circuits: make(map[string]*circuit.Circuit),
+	}
+}
+
+// get returns the circuit of the key, creating it if needed. The default circuit is returned for an empty key or once
+// there are maxKeys circuits
+func (k *circuitWrapperPubsubKeyedCircuits) get(key string, defaultCircuit *circuit.Circuit) (*circuit.Circuit, error) {
+	if key == "" {
+		return defaultCircuit, nil
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if c, ok := k.circuits[key]; ok {
+		return c, nil
+	}
+	if len(k.circuits) >= k.maxKeys {
+		return defaultCircuit, nil
+	}
+
+	name := k.name + "." + key
+	c, err := k.manager.CreateCircuit(name, k.configs...)
+	if err != nil {
+		return nil, fmt.Errorf("creating keyed circuit %s: %w", name, err)
+	}
+	k.circuits[key] = c
+	return c, nil
+}
+
+// CircuitWrapperPubsubPanicError is returned by CircuitWrapperPubsub when an embedded method panics and
+// RecoverPanics is set
+type CircuitWrapperPubsubPanicError struct {
+	// Method is the name of the wrapped method
+	Method string
+
+	// Value is the value the method panicked with
+	Value interface{}
+
+	// Stack is the stack trace of the panicking goroutine
+	Stack []byte
+}
+
+func (e *CircuitWrapperPubsubPanicError) Error() string {
+	return "panic in " + e.Method + ": " + fmt.Sprint(e.Value)
+}
+
+// Unwrap returns the value the method panicked with if it is an error
+func (e *CircuitWrapperPubsubPanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// recoverPanic recovers a panic of the embedded method into the error of the call if RecoverPanics is set. It must be
+// deferred by the call
+func (w *CircuitWrapperPubsub) recoverPanic(method string, err *error) {
+	if !w.RecoverPanics {
+		return
+	}
+
+	if r := recover(); r != nil {
+		*err = &CircuitWrapperPubsubPanicError{Method: method, Value: r, Stack: debug.Stack()}
+	}
+}
+
+// repanic panics with the CircuitWrapperPubsubPanicError of a call if Repanic is set
+func (w *CircuitWrapperPubsub) repanic(err error) {
+	var perr *CircuitWrapperPubsubPanicError
+	if w.Repanic && errors.As(err, &perr) {
+		panic(perr)
+	}
+}
+
+// bypassed returns whether a call bypasses the circuits, and reports it to OnBypass
+func (w *CircuitWrapperPubsub) bypassed(ctx context.Context, method string) bool {
+	if w.Bypass == nil || !w.Bypass(ctx) {
+		return false
+	}
+
+	if w.OnBypass != nil {
+		w.OnBypass(method)
+	}
+	return true
+}
+
+// isCanceledBadRequest returns whether the error of a call is counted as a bad request because the caller's context is
+// done. The circuit's timeout doesn't end the caller's context
+func (w *CircuitWrapperPubsub) isCanceledBadRequest(callerCtx context.Context, err error) bool {
+	return w.CanceledAsBadRequest && err != nil && callerCtx.Err() != nil
+}
+
+// circuitError wraps the error of a call in a CircuitWrapperPubsubError if the circuit rejected the call, or if it
+// failed with context.DeadlineExceeded while the caller's context isn't done
+func (w *CircuitWrapperPubsub) circuitError(ctx context.Context, c *circuit.Circuit, method string, err error) error {
+	var berr *circuit.SimpleBadRequest
+	if err == nil || errors.As(err, &berr) {
+		return err
+	}
+
+	if cerr, ok := err.(interface {
+		CircuitOpen() bool
+		ConcurrencyLimitReached() bool
+	}); ok {
+		return &CircuitWrapperPubsubError{
+			Circuit:                 c.Name(),
+			Method:                  method,
+			Err:                     err,
+			circuitOpen:             cerr.CircuitOpen(),
+			concurrencyLimitReached: cerr.ConcurrencyLimitReached(),
+		}
+	}
+
+	if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+		return &CircuitWrapperPubsubError{Circuit: c.Name(), Method: method, Err: err, timeout: true}
+	}
+
+	return err
+}
+
+// run executes the circuit with the policy. Calls are attempted once if the policy is nil
+func (p *CircuitWrapperPubsubRetryPolicy) run(ctx context.Context, c *circuit.Circuit, runFunc func(context.Context) error, fallbackFunc func(context.Context, error) error) error {
+	if p == nil || p.MaxAttempts < 2 {
+		return c.Execute(ctx, runFunc, fallbackFunc)
+	}
+
+	if p.AroundCircuit {
+		// Falling back on every attempt would stop the retries, so the fallback only gets the error of the last one
+		err := p.retry(ctx, func(ctx context.Context) error {
+			return c.Execute(ctx, runFunc, nil)
+		})
+		if err != nil && fallbackFunc != nil && !circuit.IsBadRequest(err) {
+			return fallbackFunc(ctx, err)
+		}
+		return err
+	}
+
+	return c.Execute(ctx, func(ctx context.Context) error {
+		return p.retry(ctx, runFunc)
+	}, fallbackFunc)
+}
+
+// retry calls f until it succeeds, its error isn't retried, the attempts run out, or the context would be done
+// before the next attempt
+func (p *CircuitWrapperPubsubRetryPolicy) retry(ctx context.Context, f func(context.Context) error) error {
+	backoff := p.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := f(ctx)
+		if err == nil || attempt >= p.MaxAttempts || !p.isRetried(err) {
+			return err
+		}
+
+		wait := backoff
+		if p.Jitter > 0 {
+			wait -= time.Duration(p.Jitter * rand.Float64() * float64(backoff)) // #nosec G404 jitter doesn't need a secure random
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+	}
+}
+
+// isRetried returns whether the error of an attempt is retried
+func (p *CircuitWrapperPubsubRetryPolicy) isRetried(err error) bool {
+	var berr *circuit.SimpleBadRequest
+	if errors.As(err, &berr) {
+		return false
+	}
+	if cerr, ok := err.(interface{ CircuitOpen() bool }); ok && cerr.CircuitOpen() {
+		return false
+	}
+	var perr *CircuitWrapperPubsubPanicError
+	if errors.As(err, &perr) {
+		return false
+	}
+	return p.ShouldRetry == nil || p.ShouldRetry(err)
+}
+
 var _ circuitgentest.Publisher = (*CircuitWrapperPubsub)(nil)
@@ -0,0 +1,64 @@
+// Copyright 2019 Twitch Interactive, Inc.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the License is
+// located at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// or in the "license" file accompanying this file. This file is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package circuitgentest
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// FetchRequest is a test struct carrying a context
+type FetchRequest struct {
+	ctx context.Context
+	// Key is a key
+	Key string
+}
+
+// NewFetchRequest creates a request carrying the context
+func NewFetchRequest(ctx context.Context, key string) *FetchRequest {
+	return &FetchRequest{ctx: ctx, Key: key}
+}
+
+// Context returns the context of the request
+func (r *FetchRequest) Context() context.Context {
+	return r.ctx
+}
+
+// Fetcher is an interface for testing methods whose context is not the first param
+type Fetcher interface {
+	// FetchKey is a test method with a context after the first param and should be wrapped
+	FetchKey(key string, ctx context.Context) (string, error)
+	// Fetch is a test method with a context inside a request and should be wrapped with a context accessor
+	Fetch(req *FetchRequest) (string, error)
+}
+
+// MockFetcher is a test mock for the Fetcher interface
+type MockFetcher struct {
+	mock.Mock
+}
+
+// FetchKey mocks the method
+func (m *MockFetcher) FetchKey(key string, ctx context.Context) (string, error) {
+	args := m.Called(key, ctx)
+	return args.String(0), args.Error(1)
+}
+
+// Fetch mocks the method
+func (m *MockFetcher) Fetch(req *FetchRequest) (string, error) {
+	args := m.Called(req)
+	return args.String(0), args.Error(1)
+}
+
+var _ Fetcher = (*MockFetcher)(nil)
@@ -21,3 +21,7 @@ package circuitgentest
 //go:generate circuitgen circuit --goimports=true --pkg . --name Publisher --out ./publisher.gen.go
 //go:generate circuitgen circuit --goimports=false --pkg . --name Publisher --alias PublisherCircuitV3 --out ./publishercircuitv3.gen.go --circuit-major-version 3
 //go:generate circuitgen circuit --goimports=true --pkg . --name Aggregator --out ./
+//go:generate circuitgen circuit --goimports=false --pkg . --name Store --out ./store.gen.go
+//go:generate circuitgen circuit --goimports=false --pkg . --name Fetcher --context-accessor .Context() --out ./fetcher.gen.go
+//go:generate circuitgen circuit --goimports=false --pkg . --name Publisher --alias PublisherFiltered --exclude PublishWithResult --out ./publisherfiltered.gen.go
+//go:generate circuitgen circuit --goimports=false --pkg . --name SettingsStore --out ./settingsstore.gen.go
@@ -0,0 +1,523 @@
+// Code generated by circuitgen tool. DO NOT EDIT
+
+package circuitgentest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/cep21/circuit"
+	circuitgentestconf "github.com/twitchtv/circuitgen/internal/circuitgentest/conf"
+	"math/rand"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// CircuitWrapperSettingsStoreConfig contains configuration for CircuitWrapperSettingsStore. All fields are optional
+type CircuitWrapperSettingsStoreConfig struct {
+	// ShouldSkipError determines whether an error should be skipped and have the circuit
+	// track the call as successful. This takes precedence over IsBadRequest
+	ShouldSkipError func(error) bool
+
+	// IsBadRequest is an optional bad request checker. It is useful to not count user errors as faults
+	//
+	// Both checkers receive the error of the embedded method as is, so wrapped errors can be checked with errors.Is
+	// and errors.As
+	IsBadRequest func(error) bool
+
+	// Prefix is prepended to all circuit names
+	Prefix string
+
+	// Defaults are used for all created circuits. Per-circuit configs override this
+	Defaults circuit.Config
+
+	// MaxKeys bounds the number of keyed circuits of each method. Calls with new keys use the circuit of the method once
+	// it is reached. Defaults to 1000
+	MaxKeys int
+
+	// Retry is the retry policy of all methods. Calls are attempted once if nil
+	Retry *CircuitWrapperSettingsStoreRetryPolicy
+
+	// RecoverPanics recovers panics of the embedded methods into a CircuitWrapperSettingsStorePanicError, which the
+	// circuit counts as a failure. Panics are not recovered by default
+	RecoverPanics bool
+
+	// Repanic panics again with the CircuitWrapperSettingsStorePanicError of a recovered panic after the circuit counted
+	// it, unless a fallback handled it. This has no effect without RecoverPanics
+	Repanic bool
+
+	// CanceledAsBadRequest counts calls failing after the caller's context is done as bad requests, which don't fall
+	// back and aren't retried. Otherwise the circuit counts them as interrupts, unless it ignores interrupts. Either
+	// way they don't count as failures, unlike the circuit's timeouts
+	CanceledAsBadRequest bool
+
+	// Bypass returns whether calls with the context bypass the circuits and call the embedded methods directly, ex.
+	// for admin tools. Bypassed calls aren't tracked by the circuits. Set it to circuitbypass.IsBypassed to bypass
+	// calls with contexts from circuitbypass.WithCircuitBypass. Calls aren't bypassed if nil
+	Bypass func(context.Context) bool
+
+	// OnBypass is called with the method name of every bypassed call if set, ex. to report metrics
+	OnBypass func(method string)
+
+	// CircuitSave is the configuration used for the Save circuit. This overrides values set by Defaults
+	CircuitSave circuit.Config
+	// FallbackSave is called with the params of Save and the circuit error when the call fails or
+	// the circuit is open. Its results are returned instead. Bad requests and skipped errors don't fall back
+	FallbackSave func(context.Context, circuitgentestconf.Settings, error) (circuitgentestconf.Settings, error)
+	// RetrySave is the retry policy of Save. This overrides Retry
+	RetrySave *CircuitWrapperSettingsStoreRetryPolicy
+	// KeySave returns the key of a call of Save. Calls with a non-empty key use a circuit of the key
+	// named Prefix+"SettingsStore.Save.<key>", created on first use with CircuitSave. Otherwise calls use
+	// CircuitSave
+	KeySave func(context.Context, circuitgentestconf.Settings) string
+	// ShouldSkipErrorSave overrides ShouldSkipError for Save. It receives the params of the call
+	ShouldSkipErrorSave func(context.Context, circuitgentestconf.Settings, error) bool
+	// IsBadRequestSave overrides IsBadRequest for Save. It receives the params of the call
+	IsBadRequestSave func(context.Context, circuitgentestconf.Settings, error) bool
+}
+
+// CircuitWrapperSettingsStoreRetryPolicy configures retrying failed calls of CircuitWrapperSettingsStore. Bad requests,
+// skipped errors, open circuits, and recovered panics are never retried
+type CircuitWrapperSettingsStoreRetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts of a call, including the first. Calls are attempted once if less than 2
+	MaxAttempts int
+
+	// InitialBackoff is the backoff before the first retry. It doubles for every retry after
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the backoff between retries if set
+	MaxBackoff time.Duration
+
+	// Jitter is the fraction of each backoff that is randomly subtracted from it, between 0 and 1. 1 is full jitter
+	Jitter float64
+
+	// ShouldRetry determines whether an error is retried. Defaults to retrying all errors
+	ShouldRetry func(error) bool
+
+	// AroundCircuit retries calls around the circuit, so every attempt is tracked by the circuit and has its own
+	// timeout. The fallback is called once the attempts run out. Otherwise calls are retried inside the circuit, which
+	// tracks all attempts as one call bounded by its timeout
+	AroundCircuit bool
+}
+
+// CircuitWrapperSettingsStore is a circuit wrapper for SettingsStore
+type CircuitWrapperSettingsStore struct {
+	SettingsStore
+
+	// ShouldSkipError determines whether an error should be skipped and have the circuit
+	// track the call as successful. This takes precedence over IsBadRequest
+	ShouldSkipError func(error) bool
+
+	// IsBadRequest checks whether to count a user error against the circuit. It is recommended to set this
+	IsBadRequest func(error) bool
+
+	// RecoverPanics recovers panics of the embedded methods into a CircuitWrapperSettingsStorePanicError
+	RecoverPanics bool
+
+	// Repanic panics again with recovered panics after the circuit counted them
+	Repanic bool
+
+	// CanceledAsBadRequest counts calls failing after the caller's context is done as bad requests
+	CanceledAsBadRequest bool
+
+	// Bypass returns whether calls with the context bypass the circuits
+	Bypass func(context.Context) bool
+
+	// OnBypass is called with the method name of every bypassed call
+	OnBypass func(method string)
+
+	// CircuitSave is the circuit for method Save
+	CircuitSave *circuit.Circuit
+	// FallbackSave is the optional fallback for method Save
+	FallbackSave func(context.Context, circuitgentestconf.Settings, error) (circuitgentestconf.Settings, error)
+	// RetrySave is the optional retry policy for method Save
+	RetrySave *CircuitWrapperSettingsStoreRetryPolicy
+	// KeySave is the optional key function of the keyed circuits for method Save
+	KeySave           func(context.Context, circuitgentestconf.Settings) string
+	keyedCircuitsSave *circuitWrapperSettingsStoreKeyedCircuits
+	// ShouldSkipErrorSave determines whether an error of method Save should be skipped
+	ShouldSkipErrorSave func(context.Context, circuitgentestconf.Settings, error) bool
+	// IsBadRequestSave checks whether to count an error of method Save against the circuit
+	IsBadRequestSave func(context.Context, circuitgentestconf.Settings, error) bool
+}
+
+// NewCircuitWrapperSettingsStore creates a new circuit wrapper and initializes circuits
+func NewCircuitWrapperSettingsStore(
+	manager *circuit.Manager,
+	embedded SettingsStore,
+	conf CircuitWrapperSettingsStoreConfig,
+) (*CircuitWrapperSettingsStore, error) {
+	if conf.ShouldSkipError == nil {
+		conf.ShouldSkipError = func(err error) bool {
+			return false
+		}
+	}
+
+	if conf.IsBadRequest == nil {
+		conf.IsBadRequest = func(err error) bool {
+			return false
+		}
+	}
+
+	if conf.MaxKeys <= 0 {
+		conf.MaxKeys = 1000
+	}
+
+	w := &CircuitWrapperSettingsStore{
+		SettingsStore:        embedded,
+		ShouldSkipError:      conf.ShouldSkipError,
+		IsBadRequest:         conf.IsBadRequest,
+		RecoverPanics:        conf.RecoverPanics,
+		Repanic:              conf.Repanic,
+		CanceledAsBadRequest: conf.CanceledAsBadRequest,
+		Bypass:               conf.Bypass,
+		OnBypass:             conf.OnBypass,
+		FallbackSave:         conf.FallbackSave,
+		KeySave:              conf.KeySave,
+		keyedCircuitsSave:    newCircuitWrapperSettingsStoreKeyedCircuits(manager, conf.Prefix+"SettingsStore.Save", conf.MaxKeys, conf.CircuitSave, conf.Defaults),
+	}
+
+	var err error
+	w.CircuitSave, err = manager.CreateCircuit(conf.Prefix+"SettingsStore.Save", conf.CircuitSave, conf.Defaults)
+	if err != nil {
+		return nil, err
+	}
+
+	w.RetrySave = conf.RetrySave
+	if w.RetrySave == nil {
+		w.RetrySave = conf.Retry
+	}
+
+	w.ShouldSkipErrorSave = conf.ShouldSkipErrorSave
+	if w.ShouldSkipErrorSave == nil {
+		w.ShouldSkipErrorSave = func(_ context.Context, _ circuitgentestconf.Settings, err error) bool {
+			return w.ShouldSkipError(err)
+		}
+	}
+
+	w.IsBadRequestSave = conf.IsBadRequestSave
+	if w.IsBadRequestSave == nil {
+		w.IsBadRequestSave = func(_ context.Context, _ circuitgentestconf.Settings, err error) bool {
+			return w.IsBadRequest(err)
+		}
+	}
+
+	return w, nil
+}
+
+// Save calls the embedded SettingsStore's method Save with CircuitSave
+func (w *CircuitWrapperSettingsStore) Save(ctx context.Context, p1 circuitgentestconf.Settings) (circuitgentestconf.Settings, error) {
+	callerCtx := ctx
+	if w.bypassed(callerCtx, "Save") {
+		return w.SettingsStore.Save(ctx, p1)
+	}
+
+	c := w.CircuitSave
+	if w.KeySave != nil {
+		var err error
+		if c, err = w.keyedCircuitsSave.get(w.KeySave(ctx, p1), c); err != nil {
+			var r0 circuitgentestconf.Settings
+			return r0, err
+		}
+	}
+
+	var r0 circuitgentestconf.Settings
+	var skippedErr error
+
+	var fallback func(context.Context, error) error
+	if w.FallbackSave != nil {
+		fallback = func(ctx context.Context, err error) error {
+			r0, err = w.FallbackSave(ctx, p1, err)
+			return err
+		}
+	}
+
+	err := w.RetrySave.run(callerCtx, c, func(ctx context.Context) (err error) {
+		defer w.recoverPanic("Save", &err)
+
+		r0, err = w.SettingsStore.Save(ctx, p1)
+
+		if w.isCanceledBadRequest(callerCtx, err) {
+			return &circuit.SimpleBadRequest{Err: err}
+		}
+
+		if w.ShouldSkipErrorSave(ctx, p1, err) {
+			skippedErr = err
+			return nil
+		}
+
+		if w.IsBadRequestSave(ctx, p1, err) {
+			return &circuit.SimpleBadRequest{Err: err}
+		}
+		return err
+	}, fallback)
+	err = w.circuitError(callerCtx, c, "Save", err)
+	w.repanic(err)
+
+	if skippedErr != nil {
+		err = skippedErr
+	}
+
+	// The circuit or its hooks may wrap the bad request
+	var berr *circuit.SimpleBadRequest
+	if errors.As(err, &berr) {
+		err = berr.Err
+	}
+
+	return r0, err
+}
+
+// CircuitWrapperSettingsStoreError is returned by CircuitWrapperSettingsStore when a circuit rejects a call because it is
+// open or at its concurrency limit, or when a call times out. Other errors of the embedded methods are returned as is.
+// It implements the circuit.Error interface of cep21/circuit v3
+type CircuitWrapperSettingsStoreError struct {
+	// Circuit is the name of the circuit
+	Circuit string
+
+	// Method is the name of the wrapped method
+	Method string
+
+	// Err is the error of the circuit, or of the method if it timed out
+	Err error
+
+	circuitOpen             bool
+	concurrencyLimitReached bool
+	timeout                 bool
+}
+
+func (e *CircuitWrapperSettingsStoreError) Error() string {
+	return e.Circuit + ": " + e.Err.Error()
+}
+
+// Unwrap returns the cause
+func (e *CircuitWrapperSettingsStoreError) Unwrap() error {
+	return e.Err
+}
+
+// CircuitOpen returns true if the call was rejected because the circuit is open
+func (e *CircuitWrapperSettingsStoreError) CircuitOpen() bool {
+	return e.circuitOpen
+}
+
+// ConcurrencyLimitReached returns true if the call was rejected because the circuit reached its concurrency limit
+func (e *CircuitWrapperSettingsStoreError) ConcurrencyLimitReached() bool {
+	return e.concurrencyLimitReached
+}
+
+// Timeout returns true if the call exceeded the circuit's timeout
+func (e *CircuitWrapperSettingsStoreError) Timeout() bool {
+	return e.timeout
+}
+
+// circuitWrapperSettingsStoreKeyedCircuits lazily creates the keyed circuits of a method of CircuitWrapperSettingsStore on the manager.
+// The manager can't remove circuits, so calls with new keys use the circuit of the method once there are maxKeys
+type circuitWrapperSettingsStoreKeyedCircuits struct {
+	manager *circuit.Manager
+	name    string
+	maxKeys int
+	configs []circuit.Config
+
+	mu       sync.Mutex
+	circuits map[string]*circuit.Circuit
+}
+
+func newCircuitWrapperSettingsStoreKeyedCircuits(manager *circuit.Manager, name string, maxKeys int, configs ...circuit.Config) *circuitWrapperSettingsStoreKeyedCircuits {
+	return &circuitWrapperSettingsStoreKeyedCircuits{
+		manager:  manager,
+		name:     name,
+		maxKeys:  maxKeys,
+		configs:  configs,
+		circuits: make(map[string]*circuit.Circuit),
+	}
+}
+
+// get returns the circuit of the key, creating it if needed. The default circuit is returned for an empty key or once
+// there are maxKeys circuits
+func (k *circuitWrapperSettingsStoreKeyedCircuits) get(key string, defaultCircuit *circuit.Circuit) (*circuit.Circuit, error) {
+	if key == "" {
+		return defaultCircuit, nil
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if c, ok := k.circuits[key]; ok {
+		return c, nil
+	}
+	if len(k.circuits) >= k.maxKeys {
+		return defaultCircuit, nil
+	}
+
+	name := k.name + "." + key
+	c, err := k.manager.CreateCircuit(name, k.configs...)
+	if err != nil {
+		return nil, fmt.Errorf("creating keyed circuit %s: %w", name, err)
+	}
+	k.circuits[key] = c
+	return c, nil
+}
+
+// CircuitWrapperSettingsStorePanicError is returned by CircuitWrapperSettingsStore when an embedded method panics and
+// RecoverPanics is set
+type CircuitWrapperSettingsStorePanicError struct {
+	// Method is the name of the wrapped method
+	Method string
+
+	// Value is the value the method panicked with
+	Value interface{}
+
+	// Stack is the stack trace of the panicking goroutine
+	Stack []byte
+}
+
+func (e *CircuitWrapperSettingsStorePanicError) Error() string {
+	return "panic in " + e.Method + ": " + fmt.Sprint(e.Value)
+}
+
+// Unwrap returns the value the method panicked with if it is an error
+func (e *CircuitWrapperSettingsStorePanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// recoverPanic recovers a panic of the embedded method into the error of the call if RecoverPanics is set. It must be
+// deferred by the call
+func (w *CircuitWrapperSettingsStore) recoverPanic(method string, err *error) {
+	if !w.RecoverPanics {
+		return
+	}
+
+	if r := recover(); r != nil {
+		*err = &CircuitWrapperSettingsStorePanicError{Method: method, Value: r, Stack: debug.Stack()}
+	}
+}
+
+// repanic panics with the CircuitWrapperSettingsStorePanicError of a call if Repanic is set
+func (w *CircuitWrapperSettingsStore) repanic(err error) {
+	var perr *CircuitWrapperSettingsStorePanicError
+	if w.Repanic && errors.As(err, &perr) {
+		panic(perr)
+	}
+}
+
+// bypassed returns whether a call bypasses the circuits, and reports it to OnBypass
+func (w *CircuitWrapperSettingsStore) bypassed(ctx context.Context, method string) bool {
+	if w.Bypass == nil || !w.Bypass(ctx) {
+		return false
+	}
+
+	if w.OnBypass != nil {
+		w.OnBypass(method)
+	}
+	return true
+}
+
+// isCanceledBadRequest returns whether the error of a call is counted as a bad request because the caller's context is
+// done. The circuit's timeout doesn't end the caller's context
+func (w *CircuitWrapperSettingsStore) isCanceledBadRequest(callerCtx context.Context, err error) bool {
+	return w.CanceledAsBadRequest && err != nil && callerCtx.Err() != nil
+}
+
+// circuitError wraps the error of a call in a CircuitWrapperSettingsStoreError if the circuit rejected the call, or if it
+// failed with context.DeadlineExceeded while the caller's context isn't done
+func (w *CircuitWrapperSettingsStore) circuitError(ctx context.Context, c *circuit.Circuit, method string, err error) error {
+	var berr *circuit.SimpleBadRequest
+	if err == nil || errors.As(err, &berr) {
+		return err
+	}
+
+	if cerr, ok := err.(interface {
+		CircuitOpen() bool
+		ConcurrencyLimitReached() bool
+	}); ok {
+		return &CircuitWrapperSettingsStoreError{
+			Circuit:                 c.Name(),
+			Method:                  method,
+			Err:                     err,
+			circuitOpen:             cerr.CircuitOpen(),
+			concurrencyLimitReached: cerr.ConcurrencyLimitReached(),
+		}
+	}
+
+	if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+		return &CircuitWrapperSettingsStoreError{Circuit: c.Name(), Method: method, Err: err, timeout: true}
+	}
+
+	return err
+}
+
+// run executes the circuit with the policy. Calls are attempted once if the policy is nil
+func (p *CircuitWrapperSettingsStoreRetryPolicy) run(ctx context.Context, c *circuit.Circuit, runFunc func(context.Context) error, fallbackFunc func(context.Context, error) error) error {
+	if p == nil || p.MaxAttempts < 2 {
+		return c.Execute(ctx, runFunc, fallbackFunc)
+	}
+
+	if p.AroundCircuit {
+		// Falling back on every attempt would stop the retries, so the fallback only gets the error of the last one
+		err := p.retry(ctx, func(ctx context.Context) error {
+			return c.Execute(ctx, runFunc, nil)
+		})
+		if err != nil && fallbackFunc != nil && !circuit.IsBadRequest(err) {
+			return fallbackFunc(ctx, err)
+		}
+		return err
+	}
+
+	return c.Execute(ctx, func(ctx context.Context) error {
+		return p.retry(ctx, runFunc)
+	}, fallbackFunc)
+}
+
+// retry calls f until it succeeds, its error isn't retried, the attempts run out, or the context would be done
+// before the next attempt
+func (p *CircuitWrapperSettingsStoreRetryPolicy) retry(ctx context.Context, f func(context.Context) error) error {
+	backoff := p.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := f(ctx)
+		if err == nil || attempt >= p.MaxAttempts || !p.isRetried(err) {
+			return err
+		}
+
+		wait := backoff
+		if p.Jitter > 0 {
+			wait -= time.Duration(p.Jitter * rand.Float64() * float64(backoff)) // #nosec G404 jitter doesn't need a secure random
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+	}
+}
+
+// isRetried returns whether the error of an attempt is retried
+func (p *CircuitWrapperSettingsStoreRetryPolicy) isRetried(err error) bool {
+	var berr *circuit.SimpleBadRequest
+	if errors.As(err, &berr) {
+		return false
+	}
+	if cerr, ok := err.(interface{ CircuitOpen() bool }); ok && cerr.CircuitOpen() {
+		return false
+	}
+	var perr *CircuitWrapperSettingsStorePanicError
+	if errors.As(err, &perr) {
+		return false
+	}
+	return p.ShouldRetry == nil || p.ShouldRetry(err)
+}
+
+var _ SettingsStore = (*CircuitWrapperSettingsStore)(nil)
@@ -0,0 +1,26 @@
+// Copyright 2019 Twitch Interactive, Inc.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the License is
+// located at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// or in the "license" file accompanying this file. This file is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package circuitgentest
+
+import (
+	"context"
+
+	"github.com/twitchtv/circuitgen/internal/circuitgentest/conf"
+)
+
+// SettingsStore is a test interface referencing a package named like a local variable of the wrapper. The package is
+// aliased so its types aren't shadowed
+type SettingsStore interface {
+	Save(ctx context.Context, s conf.Settings) (conf.Settings, error)
+}
@@ -0,0 +1,702 @@
+// Code generated by circuitgen tool. DO NOT EDIT
+
+package circuitgentest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/cep21/circuit"
+	"math/rand"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// CircuitWrapperStoreConfig contains configuration for CircuitWrapperStore. All fields are optional
+type CircuitWrapperStoreConfig[K comparable, V any] struct {
+	// ShouldSkipError determines whether an error should be skipped and have the circuit
+	// track the call as successful. This takes precedence over IsBadRequest
+	ShouldSkipError func(error) bool
+
+	// IsBadRequest is an optional bad request checker. It is useful to not count user errors as faults
+	//
+	// Both checkers receive the error of the embedded method as is, so wrapped errors can be checked with errors.Is
+	// and errors.As
+	IsBadRequest func(error) bool
+
+	// Prefix is prepended to all circuit names
+	Prefix string
+
+	// Defaults are used for all created circuits. Per-circuit configs override this
+	Defaults circuit.Config
+
+	// MaxKeys bounds the number of keyed circuits of each method. Calls with new keys use the circuit of the method once
+	// it is reached. Defaults to 1000
+	MaxKeys int
+
+	// Retry is the retry policy of all methods. Calls are attempted once if nil
+	Retry *CircuitWrapperStoreRetryPolicy
+
+	// RecoverPanics recovers panics of the embedded methods into a CircuitWrapperStorePanicError, which the
+	// circuit counts as a failure. Panics are not recovered by default
+	RecoverPanics bool
+
+	// Repanic panics again with the CircuitWrapperStorePanicError of a recovered panic after the circuit counted
+	// it, unless a fallback handled it. This has no effect without RecoverPanics
+	Repanic bool
+
+	// CanceledAsBadRequest counts calls failing after the caller's context is done as bad requests, which don't fall
+	// back and aren't retried. Otherwise the circuit counts them as interrupts, unless it ignores interrupts. Either
+	// way they don't count as failures, unlike the circuit's timeouts
+	CanceledAsBadRequest bool
+
+	// Bypass returns whether calls with the context bypass the circuits and call the embedded methods directly, ex.
+	// for admin tools. Bypassed calls aren't tracked by the circuits. Set it to circuitbypass.IsBypassed to bypass
+	// calls with contexts from circuitbypass.WithCircuitBypass. Calls aren't bypassed if nil
+	Bypass func(context.Context) bool
+
+	// OnBypass is called with the method name of every bypassed call if set, ex. to report metrics
+	OnBypass func(method string)
+
+	// CircuitGet is the configuration used for the Get circuit. This overrides values set by Defaults
+	CircuitGet circuit.Config
+	// FallbackGet is called with the params of Get and the circuit error when the call fails or
+	// the circuit is open. Its results are returned instead. Bad requests and skipped errors don't fall back
+	FallbackGet func(context.Context, K, error) (V, error)
+	// RetryGet is the retry policy of Get. This overrides Retry
+	RetryGet *CircuitWrapperStoreRetryPolicy
+	// KeyGet returns the key of a call of Get. Calls with a non-empty key use a circuit of the key
+	// named Prefix+"Store.Get.<key>", created on first use with CircuitGet. Otherwise calls use
+	// CircuitGet
+	KeyGet func(context.Context, K) string
+	// ShouldSkipErrorGet overrides ShouldSkipError for Get. It receives the params of the call
+	ShouldSkipErrorGet func(context.Context, K, error) bool
+	// IsBadRequestGet overrides IsBadRequest for Get. It receives the params of the call
+	IsBadRequestGet func(context.Context, K, error) bool
+	// HedgeGet is the delay after which a call of Get that hasn't finished is hedged with a second
+	// call. The first to succeed is returned and the other is cancelled. Calls aren't hedged if not positive. Defaults to 50ms
+	HedgeGet time.Duration
+	// CircuitPut is the configuration used for the Put circuit. This overrides values set by Defaults
+	CircuitPut circuit.Config
+	// FallbackPut is called with the params of Put and the circuit error when the call fails or
+	// the circuit is open. Its results are returned instead. Bad requests and skipped errors don't fall back
+	FallbackPut func(context.Context, K, V, error) error
+	// RetryPut is the retry policy of Put. This overrides Retry
+	RetryPut *CircuitWrapperStoreRetryPolicy
+	// KeyPut returns the key of a call of Put. Calls with a non-empty key use a circuit of the key
+	// named Prefix+"Store.Put.<key>", created on first use with CircuitPut. Otherwise calls use
+	// CircuitPut
+	KeyPut func(context.Context, K, V) string
+	// ShouldSkipErrorPut overrides ShouldSkipError for Put. It receives the params of the call
+	ShouldSkipErrorPut func(context.Context, K, V, error) bool
+	// IsBadRequestPut overrides IsBadRequest for Put. It receives the params of the call
+	IsBadRequestPut func(context.Context, K, V, error) bool
+}
+
+// CircuitWrapperStoreRetryPolicy configures retrying failed calls of CircuitWrapperStore. Bad requests,
+// skipped errors, open circuits, and recovered panics are never retried
+type CircuitWrapperStoreRetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts of a call, including the first. Calls are attempted once if less than 2
+	MaxAttempts int
+
+	// InitialBackoff is the backoff before the first retry. It doubles for every retry after
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the backoff between retries if set
+	MaxBackoff time.Duration
+
+	// Jitter is the fraction of each backoff that is randomly subtracted from it, between 0 and 1. 1 is full jitter
+	Jitter float64
+
+	// ShouldRetry determines whether an error is retried. Defaults to retrying all errors
+	ShouldRetry func(error) bool
+
+	// AroundCircuit retries calls around the circuit, so every attempt is tracked by the circuit and has its own
+	// timeout. The fallback is called once the attempts run out. Otherwise calls are retried inside the circuit, which
+	// tracks all attempts as one call bounded by its timeout
+	AroundCircuit bool
+}
+
+// CircuitWrapperStore is a circuit wrapper for Store[K, V]
+type CircuitWrapperStore[K comparable, V any] struct {
+	Store[K, V]
+
+	// ShouldSkipError determines whether an error should be skipped and have the circuit
+	// track the call as successful. This takes precedence over IsBadRequest
+	ShouldSkipError func(error) bool
+
+	// IsBadRequest checks whether to count a user error against the circuit. It is recommended to set this
+	IsBadRequest func(error) bool
+
+	// RecoverPanics recovers panics of the embedded methods into a CircuitWrapperStorePanicError
+	RecoverPanics bool
+
+	// Repanic panics again with recovered panics after the circuit counted them
+	Repanic bool
+
+	// CanceledAsBadRequest counts calls failing after the caller's context is done as bad requests
+	CanceledAsBadRequest bool
+
+	// Bypass returns whether calls with the context bypass the circuits
+	Bypass func(context.Context) bool
+
+	// OnBypass is called with the method name of every bypassed call
+	OnBypass func(method string)
+
+	// CircuitGet is the circuit for method Get
+	CircuitGet *circuit.Circuit
+	// FallbackGet is the optional fallback for method Get
+	FallbackGet func(context.Context, K, error) (V, error)
+	// RetryGet is the optional retry policy for method Get
+	RetryGet *CircuitWrapperStoreRetryPolicy
+	// KeyGet is the optional key function of the keyed circuits for method Get
+	KeyGet           func(context.Context, K) string
+	keyedCircuitsGet *circuitWrapperStoreKeyedCircuits
+	// ShouldSkipErrorGet determines whether an error of method Get should be skipped
+	ShouldSkipErrorGet func(context.Context, K, error) bool
+	// IsBadRequestGet checks whether to count an error of method Get against the circuit
+	IsBadRequestGet func(context.Context, K, error) bool
+	// HedgeGet is the delay before hedging calls of method Get. Calls aren't hedged if not positive
+	HedgeGet time.Duration
+	// CircuitPut is the circuit for method Put
+	CircuitPut *circuit.Circuit
+	// FallbackPut is the optional fallback for method Put
+	FallbackPut func(context.Context, K, V, error) error
+	// RetryPut is the optional retry policy for method Put
+	RetryPut *CircuitWrapperStoreRetryPolicy
+	// KeyPut is the optional key function of the keyed circuits for method Put
+	KeyPut           func(context.Context, K, V) string
+	keyedCircuitsPut *circuitWrapperStoreKeyedCircuits
+	// ShouldSkipErrorPut determines whether an error of method Put should be skipped
+	ShouldSkipErrorPut func(context.Context, K, V, error) bool
+	// IsBadRequestPut checks whether to count an error of method Put against the circuit
+	IsBadRequestPut func(context.Context, K, V, error) bool
+}
+
+// NewCircuitWrapperStore creates a new circuit wrapper and initializes circuits
+func NewCircuitWrapperStore[K comparable, V any](
+	manager *circuit.Manager,
+	embedded Store[K, V],
+	conf CircuitWrapperStoreConfig[K, V],
+) (*CircuitWrapperStore[K, V], error) {
+	if conf.ShouldSkipError == nil {
+		conf.ShouldSkipError = func(err error) bool {
+			return false
+		}
+	}
+
+	if conf.IsBadRequest == nil {
+		conf.IsBadRequest = func(err error) bool {
+			return false
+		}
+	}
+
+	if conf.MaxKeys <= 0 {
+		conf.MaxKeys = 1000
+	}
+
+	if conf.HedgeGet == 0 {
+		conf.HedgeGet = 50 * time.Millisecond
+	}
+
+	w := &CircuitWrapperStore[K, V]{
+		Store:                embedded,
+		ShouldSkipError:      conf.ShouldSkipError,
+		IsBadRequest:         conf.IsBadRequest,
+		RecoverPanics:        conf.RecoverPanics,
+		Repanic:              conf.Repanic,
+		CanceledAsBadRequest: conf.CanceledAsBadRequest,
+		Bypass:               conf.Bypass,
+		OnBypass:             conf.OnBypass,
+		FallbackGet:          conf.FallbackGet,
+		KeyGet:               conf.KeyGet,
+		keyedCircuitsGet:     newCircuitWrapperStoreKeyedCircuits(manager, conf.Prefix+"Store.Get", conf.MaxKeys, conf.CircuitGet, conf.Defaults),
+		HedgeGet:             conf.HedgeGet,
+		FallbackPut:          conf.FallbackPut,
+		KeyPut:               conf.KeyPut,
+		keyedCircuitsPut:     newCircuitWrapperStoreKeyedCircuits(manager, conf.Prefix+"Store.Put", conf.MaxKeys, conf.CircuitPut, conf.Defaults),
+	}
+
+	var err error
+	w.CircuitGet, err = manager.CreateCircuit(conf.Prefix+"Store.Get", conf.CircuitGet, conf.Defaults)
+	if err != nil {
+		return nil, err
+	}
+
+	w.RetryGet = conf.RetryGet
+	if w.RetryGet == nil {
+		w.RetryGet = conf.Retry
+	}
+
+	w.ShouldSkipErrorGet = conf.ShouldSkipErrorGet
+	if w.ShouldSkipErrorGet == nil {
+		w.ShouldSkipErrorGet = func(_ context.Context, _ K, err error) bool {
+			return w.ShouldSkipError(err)
+		}
+	}
+
+	w.IsBadRequestGet = conf.IsBadRequestGet
+	if w.IsBadRequestGet == nil {
+		w.IsBadRequestGet = func(_ context.Context, _ K, err error) bool {
+			return w.IsBadRequest(err)
+		}
+	}
+
+	w.CircuitPut, err = manager.CreateCircuit(conf.Prefix+"Store.Put", conf.CircuitPut, conf.Defaults)
+	if err != nil {
+		return nil, err
+	}
+
+	w.RetryPut = conf.RetryPut
+	if w.RetryPut == nil {
+		w.RetryPut = conf.Retry
+	}
+
+	w.ShouldSkipErrorPut = conf.ShouldSkipErrorPut
+	if w.ShouldSkipErrorPut == nil {
+		w.ShouldSkipErrorPut = func(_ context.Context, _ K, _ V, err error) bool {
+			return w.ShouldSkipError(err)
+		}
+	}
+
+	w.IsBadRequestPut = conf.IsBadRequestPut
+	if w.IsBadRequestPut == nil {
+		w.IsBadRequestPut = func(_ context.Context, _ K, _ V, err error) bool {
+			return w.IsBadRequest(err)
+		}
+	}
+
+	return w, nil
+}
+
+// Get calls the embedded Store[K, V]'s method Get with CircuitGet. Calls are
+// hedged after HedgeGet
+func (w *CircuitWrapperStore[K, V]) Get(ctx context.Context, p1 K) (V, error) {
+	callerCtx := ctx
+	if w.bypassed(callerCtx, "Get") {
+		return w.Store.Get(ctx, p1)
+	}
+
+	c := w.CircuitGet
+	if w.KeyGet != nil {
+		var err error
+		if c, err = w.keyedCircuitsGet.get(w.KeyGet(ctx, p1), c); err != nil {
+			var r0 V
+			return r0, err
+		}
+	}
+
+	var r0 [2]V
+	var skippedErr [2]error
+
+	n, err := w.hedge(callerCtx, w.HedgeGet, func(ctx context.Context, i int) error {
+		var fallback func(context.Context, error) error
+		if w.FallbackGet != nil {
+			fallback = func(ctx context.Context, err error) error {
+				r0[i], err = w.FallbackGet(ctx, p1, err)
+				return err
+			}
+		}
+
+		return w.RetryGet.run(ctx, c, func(ctx context.Context) (err error) {
+			defer w.recoverPanic("Get", &err)
+
+			r0[i], err = w.Store.Get(ctx, p1)
+
+			if w.isCanceledBadRequest(callerCtx, err) {
+				return &circuit.SimpleBadRequest{Err: err}
+			}
+
+			if w.ShouldSkipErrorGet(ctx, p1, err) {
+				skippedErr[i] = err
+				return nil
+			}
+
+			if w.IsBadRequestGet(ctx, p1, err) {
+				return &circuit.SimpleBadRequest{Err: err}
+			}
+			return err
+		}, fallback)
+	})
+	err = w.circuitError(callerCtx, c, "Get", err)
+	w.repanic(err)
+
+	if skippedErr[n] != nil {
+		err = skippedErr[n]
+	}
+
+	// The circuit or its hooks may wrap the bad request
+	var berr *circuit.SimpleBadRequest
+	if errors.As(err, &berr) {
+		err = berr.Err
+	}
+
+	return r0[n], err
+}
+
+// Put calls the embedded Store[K, V]'s method Put with CircuitPut
+func (w *CircuitWrapperStore[K, V]) Put(ctx context.Context, p1 K, p2 V) error {
+	callerCtx := ctx
+	if w.bypassed(callerCtx, "Put") {
+		return w.Store.Put(ctx, p1, p2)
+	}
+
+	c := w.CircuitPut
+	if w.KeyPut != nil {
+		var err error
+		if c, err = w.keyedCircuitsPut.get(w.KeyPut(ctx, p1, p2), c); err != nil {
+			return err
+		}
+	}
+
+	var skippedErr error
+
+	var fallback func(context.Context, error) error
+	if w.FallbackPut != nil {
+		fallback = func(ctx context.Context, err error) error {
+			return w.FallbackPut(ctx, p1, p2, err)
+		}
+	}
+
+	err := w.RetryPut.run(callerCtx, c, func(ctx context.Context) (err error) {
+		defer w.recoverPanic("Put", &err)
+
+		err = w.Store.Put(ctx, p1, p2)
+
+		if w.isCanceledBadRequest(callerCtx, err) {
+			return &circuit.SimpleBadRequest{Err: err}
+		}
+
+		if w.ShouldSkipErrorPut(ctx, p1, p2, err) {
+			skippedErr = err
+			return nil
+		}
+
+		if w.IsBadRequestPut(ctx, p1, p2, err) {
+			return &circuit.SimpleBadRequest{Err: err}
+		}
+		return err
+	}, fallback)
+	err = w.circuitError(callerCtx, c, "Put", err)
+	w.repanic(err)
+
+	if skippedErr != nil {
+		err = skippedErr
+	}
+
+	// The circuit or its hooks may wrap the bad request
+	var berr *circuit.SimpleBadRequest
+	if errors.As(err, &berr) {
+		err = berr.Err
+	}
+
+	return err
+}
+
+// hedge calls attempt with the index 0, and again with the index 1 if the first call hasn't finished after the delay.
+// It returns the index and error of the first attempt to succeed, or of the last to fail. The context of an attempt
+// still running is cancelled. A panic of an attempt is raised again in the calling goroutine. Calls aren't hedged if
+// the delay isn't positive
+func (w *CircuitWrapperStore[K, V]) hedge(ctx context.Context, delay time.Duration, attempt func(context.Context, int) error) (int, error) {
+	if delay <= 0 {
+		return 0, attempt(ctx, 0)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		i         int
+		err       error
+		recovered interface{}
+	}
+	// Buffered so the cancelled attempt doesn't block
+	results := make(chan result, 2)
+	call := func(i int) {
+		// Attempts run in their own goroutines, where a panic would crash the program instead of reaching the caller
+		defer func() {
+			if r := recover(); r != nil {
+				results <- result{i: i, recovered: r}
+			}
+		}()
+		results <- result{i: i, err: attempt(ctx, i)}
+	}
+	go call(0)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	started, finished := 1, 0
+	for {
+		select {
+		case <-timer.C:
+			started++
+			go call(1)
+		case r := <-results:
+			if r.recovered != nil {
+				panic(r.recovered)
+			}
+			finished++
+			if r.err == nil || finished == started {
+				return r.i, r.err
+			}
+		}
+	}
+}
+
+// CircuitWrapperStoreError is returned by CircuitWrapperStore when a circuit rejects a call because it is
+// open or at its concurrency limit, or when a call times out. Other errors of the embedded methods are returned as is.
+// It implements the circuit.Error interface of cep21/circuit v3
+type CircuitWrapperStoreError struct {
+	// Circuit is the name of the circuit
+	Circuit string
+
+	// Method is the name of the wrapped method
+	Method string
+
+	// Err is the error of the circuit, or of the method if it timed out
+	Err error
+
+	circuitOpen             bool
+	concurrencyLimitReached bool
+	timeout                 bool
+}
+
+func (e *CircuitWrapperStoreError) Error() string {
+	return e.Circuit + ": " + e.Err.Error()
+}
+
+// Unwrap returns the cause
+func (e *CircuitWrapperStoreError) Unwrap() error {
+	return e.Err
+}
+
+// CircuitOpen returns true if the call was rejected because the circuit is open
+func (e *CircuitWrapperStoreError) CircuitOpen() bool {
+	return e.circuitOpen
+}
+
+// ConcurrencyLimitReached returns true if the call was rejected because the circuit reached its concurrency limit
+func (e *CircuitWrapperStoreError) ConcurrencyLimitReached() bool {
+	return e.concurrencyLimitReached
+}
+
+// Timeout returns true if the call exceeded the circuit's timeout
+func (e *CircuitWrapperStoreError) Timeout() bool {
+	return e.timeout
+}
+
+// circuitWrapperStoreKeyedCircuits lazily creates the keyed circuits of a method of CircuitWrapperStore on the manager.
+// The manager can't remove circuits, so calls with new keys use the circuit of the method once there are maxKeys
+type circuitWrapperStoreKeyedCircuits struct {
+	manager *circuit.Manager
+	name    string
+	maxKeys int
+	configs []circuit.Config
+
+	mu       sync.Mutex
+	circuits map[string]*circuit.Circuit
+}
+
+func newCircuitWrapperStoreKeyedCircuits(manager *circuit.Manager, name string, maxKeys int, configs ...circuit.Config) *circuitWrapperStoreKeyedCircuits {
+	return &circuitWrapperStoreKeyedCircuits{
+		manager:  manager,
+		name:     name,
+		maxKeys:  maxKeys,
+		configs:  configs,
+		circuits: make(map[string]*circuit.Circuit),
+	}
+}
+
+// get returns the circuit of the key, creating it if needed. The default circuit is returned for an empty key or once
+// there are maxKeys circuits
+func (k *circuitWrapperStoreKeyedCircuits) get(key string, defaultCircuit *circuit.Circuit) (*circuit.Circuit, error) {
+	if key == "" {
+		return defaultCircuit, nil
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if c, ok := k.circuits[key]; ok {
+		return c, nil
+	}
+	if len(k.circuits) >= k.maxKeys {
+		return defaultCircuit, nil
+	}
+
+	name := k.name + "." + key
+	c, err := k.manager.CreateCircuit(name, k.configs...)
+	if err != nil {
+		return nil, fmt.Errorf("creating keyed circuit %s: %w", name, err)
+	}
+	k.circuits[key] = c
+	return c, nil
+}
+
+// CircuitWrapperStorePanicError is returned by CircuitWrapperStore when an embedded method panics and
+// RecoverPanics is set
+type CircuitWrapperStorePanicError struct {
+	// Method is the name of the wrapped method
+	Method string
+
+	// Value is the value the method panicked with
+	Value interface{}
+
+	// Stack is the stack trace of the panicking goroutine
+	Stack []byte
+}
+
+func (e *CircuitWrapperStorePanicError) Error() string {
+	return "panic in " + e.Method + ": " + fmt.Sprint(e.Value)
+}
+
+// Unwrap returns the value the method panicked with if it is an error
+func (e *CircuitWrapperStorePanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// recoverPanic recovers a panic of the embedded method into the error of the call if RecoverPanics is set. It must be
+// deferred by the call
+func (w *CircuitWrapperStore[K, V]) recoverPanic(method string, err *error) {
+	if !w.RecoverPanics {
+		return
+	}
+
+	if r := recover(); r != nil {
+		*err = &CircuitWrapperStorePanicError{Method: method, Value: r, Stack: debug.Stack()}
+	}
+}
+
+// repanic panics with the CircuitWrapperStorePanicError of a call if Repanic is set
+func (w *CircuitWrapperStore[K, V]) repanic(err error) {
+	var perr *CircuitWrapperStorePanicError
+	if w.Repanic && errors.As(err, &perr) {
+		panic(perr)
+	}
+}
+
+// bypassed returns whether a call bypasses the circuits, and reports it to OnBypass
+func (w *CircuitWrapperStore[K, V]) bypassed(ctx context.Context, method string) bool {
+	if w.Bypass == nil || !w.Bypass(ctx) {
+		return false
+	}
+
+	if w.OnBypass != nil {
+		w.OnBypass(method)
+	}
+	return true
+}
+
+// isCanceledBadRequest returns whether the error of a call is counted as a bad request because the caller's context is
+// done. The circuit's timeout doesn't end the caller's context
+func (w *CircuitWrapperStore[K, V]) isCanceledBadRequest(callerCtx context.Context, err error) bool {
+	return w.CanceledAsBadRequest && err != nil && callerCtx.Err() != nil
+}
+
+// circuitError wraps the error of a call in a CircuitWrapperStoreError if the circuit rejected the call, or if it
+// failed with context.DeadlineExceeded while the caller's context isn't done
+func (w *CircuitWrapperStore[K, V]) circuitError(ctx context.Context, c *circuit.Circuit, method string, err error) error {
+	var berr *circuit.SimpleBadRequest
+	if err == nil || errors.As(err, &berr) {
+		return err
+	}
+
+	if cerr, ok := err.(interface {
+		CircuitOpen() bool
+		ConcurrencyLimitReached() bool
+	}); ok {
+		return &CircuitWrapperStoreError{
+			Circuit:                 c.Name(),
+			Method:                  method,
+			Err:                     err,
+			circuitOpen:             cerr.CircuitOpen(),
+			concurrencyLimitReached: cerr.ConcurrencyLimitReached(),
+		}
+	}
+
+	if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+		return &CircuitWrapperStoreError{Circuit: c.Name(), Method: method, Err: err, timeout: true}
+	}
+
+	return err
+}
+
+// run executes the circuit with the policy. Calls are attempted once if the policy is nil
+func (p *CircuitWrapperStoreRetryPolicy) run(ctx context.Context, c *circuit.Circuit, runFunc func(context.Context) error, fallbackFunc func(context.Context, error) error) error {
+	if p == nil || p.MaxAttempts < 2 {
+		return c.Execute(ctx, runFunc, fallbackFunc)
+	}
+
+	if p.AroundCircuit {
+		// Falling back on every attempt would stop the retries, so the fallback only gets the error of the last one
+		err := p.retry(ctx, func(ctx context.Context) error {
+			return c.Execute(ctx, runFunc, nil)
+		})
+		if err != nil && fallbackFunc != nil && !circuit.IsBadRequest(err) {
+			return fallbackFunc(ctx, err)
+		}
+		return err
+	}
+
+	return c.Execute(ctx, func(ctx context.Context) error {
+		return p.retry(ctx, runFunc)
+	}, fallbackFunc)
+}
+
+// retry calls f until it succeeds, its error isn't retried, the attempts run out, or the context would be done
+// before the next attempt
+func (p *CircuitWrapperStoreRetryPolicy) retry(ctx context.Context, f func(context.Context) error) error {
+	backoff := p.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := f(ctx)
+		if err == nil || attempt >= p.MaxAttempts || !p.isRetried(err) {
+			return err
+		}
+
+		wait := backoff
+		if p.Jitter > 0 {
+			wait -= time.Duration(p.Jitter * rand.Float64() * float64(backoff)) // #nosec G404 jitter doesn't need a secure random
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+	}
+}
+
+// isRetried returns whether the error of an attempt is retried
+func (p *CircuitWrapperStoreRetryPolicy) isRetried(err error) bool {
+	var berr *circuit.SimpleBadRequest
+	if errors.As(err, &berr) {
+		return false
+	}
+	if cerr, ok := err.(interface{ CircuitOpen() bool }); ok && cerr.CircuitOpen() {
+		return false
+	}
+	var perr *CircuitWrapperStorePanicError
+	if errors.As(err, &perr) {
+		return false
+	}
+	return p.ShouldRetry == nil || p.ShouldRetry(err)
+}
+
+func _[K comparable, V any]() {
+	var _ Store[K, V] = (*CircuitWrapperStore[K, V])(nil)
+}
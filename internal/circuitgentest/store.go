@@ -0,0 +1,60 @@
+// Copyright 2019 Twitch Interactive, Inc.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the License is
+// located at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// or in the "license" file accompanying this file. This file is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package circuitgentest
+
+import (
+	"context"
+)
+
+// Store is a generic interface for testing generation with type parameters
+type Store[K comparable, V any] interface {
+	// Get is a test method and should be wrapped. Its calls are hedged after 50ms by default
+	//
+	//circuitgen:hedge=50ms
+	Get(ctx context.Context, key K) (V, error)
+	// Put is a test method and should be wrapped
+	Put(ctx context.Context, key K, value V) error
+	// Len is a test method and should not be wrapped
+	Len() int
+}
+
+// MapStore is a generic test struct implementing Store
+type MapStore[K comparable, V any] struct {
+	PutError error
+	items    map[K]V
+}
+
+// Get returns the value stored at key
+func (s *MapStore[K, V]) Get(ctx context.Context, key K) (V, error) {
+	return s.items[key], nil
+}
+
+// Put stores the value at key
+func (s *MapStore[K, V]) Put(ctx context.Context, key K, value V) error {
+	if s.PutError != nil {
+		return s.PutError
+	}
+	if s.items == nil {
+		s.items = map[K]V{}
+	}
+	s.items[key] = value
+	return nil
+}
+
+// Len returns the number of stored values
+func (s *MapStore[K, V]) Len() int {
+	return len(s.items)
+}
+
+var _ Store[string, int] = (*MapStore[string, int])(nil)
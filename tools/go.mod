@@ -0,0 +1,25 @@
+module github.com/twitchtv/circuitgen/tools
+
+go 1.25.0
+
+require (
+	github.com/kisielk/errcheck v1.10.0
+	github.com/securego/gosec/v2 v2.18.2
+	golang.org/x/lint v0.0.0-20241112194109-818c5a804067
+	golang.org/x/tools v0.47.0
+	honnef.co/go/tools v0.7.0
+)
+
+require (
+	github.com/BurntSushi/toml v1.4.1-0.20240526193622-a339e1f7089c // indirect
+	github.com/ccojocar/zxcvbn-go v1.0.1 // indirect
+	github.com/google/uuid v1.3.1 // indirect
+	github.com/gookit/color v1.5.4 // indirect
+	github.com/xo/terminfo v0.0.0-20210125001918-ca9a967f8778 // indirect
+	golang.org/x/exp/typeparams v0.0.0-20231108232855-2478ac86f678 // indirect
+	golang.org/x/mod v0.37.0 // indirect
+	golang.org/x/sync v0.21.0 // indirect
+	golang.org/x/sys v0.46.0 // indirect
+	golang.org/x/telemetry v0.0.0-20260625142307-59b4966ccb57 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -11,13 +11,15 @@
 // express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 
-// +build tools
+//go:build tools
 
-package main
+// Package tools pins the versions of the linters run by make lint. It lives in
+// its own module so that the linters' dependencies stay out of circuitgen's.
+package tools
 
 import (
 	_ "github.com/kisielk/errcheck"
-	_ "github.com/securego/gosec"
+	_ "github.com/securego/gosec/v2/cmd/gosec"
 	_ "golang.org/x/lint/golint"
 	_ "golang.org/x/tools/cmd/goimports"
 	_ "honnef.co/go/tools/cmd/staticcheck"